@@ -0,0 +1,309 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is matched by errors.Is for every CircuitOpenError
+var ErrCircuitOpen = errors.New("httpclient: circuit open")
+
+// CircuitOpenError is returned while a circuit is open
+type CircuitOpenError struct {
+	Key       string
+	OpenUntil time.Time
+}
+
+// Error implements error
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("httpclient: circuit open for %s until %s", e.Key, e.OpenUntil.Format(time.RFC3339))
+}
+
+// Is makes errors.Is(err, ErrCircuitOpen) match
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// BreakerState is the state of a circuit
+type BreakerState int
+
+const (
+	// StateClosed lets requests through and tracks their failure rate
+	StateClosed BreakerState = iota
+	// StateOpen rejects requests immediately
+	StateOpen
+	// StateHalfOpen lets a limited number of trial requests through
+	StateHalfOpen
+)
+
+// String returns the name of the state
+func (s BreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerOptions configures a Breaker
+type BreakerOptions struct {
+	// KeyFunc selects the circuit of a request, defaults to its host
+	KeyFunc func(*http.Request) string
+	// IsFailure classifies an outcome, defaults to transport errors and
+	// 5xx responses. Requests cancelled by their caller are not recorded.
+	IsFailure func(*Response, error) bool
+
+	Window           time.Duration // failure-rate window, defaults to 10s
+	MinRequests      int           // requests in the window before tripping, defaults to 10
+	FailureRate      float64       // failure ratio that opens the circuit, defaults to 0.5
+	OpenTimeout      time.Duration // time spent open before half-open, defaults to 30s
+	HalfOpenRequests int           // successful trials needed to close, defaults to 1
+
+	OnStateChange func(key string, from, to BreakerState)
+}
+
+// windowBuckets is the number of slots of the sliding failure window
+const windowBuckets = 10
+
+// Breaker is a set of circuit breakers keyed by upstream
+type Breaker struct {
+	mu       sync.Mutex
+	options  BreakerOptions
+	circuits map[string]*circuit
+	changes  []stateChange
+}
+
+// stateChange is a transition waiting to be reported to OnStateChange
+type stateChange struct {
+	key      string
+	from, to BreakerState
+}
+
+// circuit is the state of a single key
+type circuit struct {
+	state      BreakerState
+	generation uint64
+	openedAt   time.Time
+	trials     int
+	successes  int
+	buckets    [windowBuckets]windowBucket
+}
+
+// windowBucket counts outcomes within one slot of the window
+type windowBucket struct {
+	start    time.Time
+	total    int
+	failures int
+}
+
+// NewBreaker creates a new circuit breaker
+func NewBreaker(options *BreakerOptions) *Breaker {
+	b := &Breaker{circuits: make(map[string]*circuit)}
+	if options != nil {
+		b.options = *options
+	}
+	if b.options.KeyFunc == nil {
+		b.options.KeyFunc = func(req *http.Request) string { return req.URL.Host }
+	}
+	if b.options.IsFailure == nil {
+		b.options.IsFailure = func(resp *Response, err error) bool {
+			return err != nil || resp.StatusCode >= 500
+		}
+	}
+	if b.options.Window <= 0 {
+		b.options.Window = 10 * time.Second
+	}
+	if b.options.MinRequests <= 0 {
+		b.options.MinRequests = 10
+	}
+	if b.options.FailureRate <= 0 {
+		b.options.FailureRate = 0.5
+	}
+	if b.options.OpenTimeout <= 0 {
+		b.options.OpenTimeout = 30 * time.Second
+	}
+	if b.options.HalfOpenRequests <= 0 {
+		b.options.HalfOpenRequests = 1
+	}
+	return b
+}
+
+// CircuitBreakerMiddleware rejects requests with a CircuitOpenError while
+// the circuit of their key is open
+func CircuitBreakerMiddleware(breaker *Breaker) Middleware {
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*Response, error) {
+			key := breaker.options.KeyFunc(req)
+			generation, err := breaker.allow(key)
+			if err != nil {
+				return nil, err
+			}
+
+			resp, err := next(req)
+			if errors.Is(err, context.Canceled) {
+				// The caller gave up, which says nothing about the upstream
+				breaker.release(key, generation)
+				return resp, err
+			}
+			breaker.record(key, generation, breaker.options.IsFailure(resp, err))
+			return resp, err
+		}
+	}
+}
+
+// State returns the current state of the circuit for key
+func (b *Breaker) State(key string) BreakerState {
+	b.mu.Lock()
+	defer b.unlock()
+
+	c, ok := b.circuits[key]
+	if !ok {
+		return StateClosed
+	}
+	b.expire(key, c, time.Now())
+	return c.state
+}
+
+// Reset closes the circuit for key and forgets its history
+func (b *Breaker) Reset(key string) {
+	b.mu.Lock()
+	defer b.unlock()
+
+	if c, ok := b.circuits[key]; ok {
+		b.transition(key, c, StateClosed, time.Now())
+	}
+}
+
+// allow admits a request for key and returns the circuit generation it
+// belongs to
+func (b *Breaker) allow(key string) (uint64, error) {
+	b.mu.Lock()
+	defer b.unlock()
+
+	now := time.Now()
+	c, ok := b.circuits[key]
+	if !ok {
+		c = &circuit{}
+		b.circuits[key] = c
+	}
+	b.expire(key, c, now)
+
+	switch c.state {
+	case StateOpen:
+		return 0, &CircuitOpenError{Key: key, OpenUntil: c.openedAt.Add(b.options.OpenTimeout)}
+	case StateHalfOpen:
+		if c.trials >= b.options.HalfOpenRequests {
+			return 0, &CircuitOpenError{Key: key, OpenUntil: now}
+		}
+		c.trials++
+	}
+	return c.generation, nil
+}
+
+// record accounts the outcome of a request admitted by allow
+func (b *Breaker) record(key string, generation uint64, failed bool) {
+	b.mu.Lock()
+	defer b.unlock()
+
+	c := b.circuits[key]
+	if c.generation != generation {
+		return
+	}
+	now := time.Now()
+
+	switch c.state {
+	case StateHalfOpen:
+		if failed {
+			b.transition(key, c, StateOpen, now)
+			return
+		}
+		c.successes++
+		if c.successes >= b.options.HalfOpenRequests {
+			b.transition(key, c, StateClosed, now)
+		}
+
+	case StateClosed:
+		slot := b.options.Window / windowBuckets
+		if slot <= 0 {
+			slot = 1 // windows shorter than windowBuckets nanoseconds
+		}
+		bucket := &c.buckets[now.UnixNano()/int64(slot)%windowBuckets]
+		if now.Sub(bucket.start) >= slot {
+			*bucket = windowBucket{start: time.Unix(0, now.UnixNano()/int64(slot)*int64(slot))}
+		}
+		bucket.total++
+		if failed {
+			bucket.failures++
+		}
+
+		total, failures := 0, 0
+		for _, bucket := range c.buckets {
+			if now.Sub(bucket.start) < b.options.Window {
+				total += bucket.total
+				failures += bucket.failures
+			}
+		}
+		if total >= b.options.MinRequests && float64(failures)/float64(total) >= b.options.FailureRate {
+			b.transition(key, c, StateOpen, now)
+		}
+	}
+}
+
+// release gives back the half-open trial of a request admitted by allow
+// without recording its outcome
+func (b *Breaker) release(key string, generation uint64) {
+	b.mu.Lock()
+	defer b.unlock()
+
+	c := b.circuits[key]
+	if c.generation == generation && c.state == StateHalfOpen && c.trials > 0 {
+		c.trials--
+	}
+}
+
+// expire moves an open circuit to half-open once its timeout has passed
+func (b *Breaker) expire(key string, c *circuit, now time.Time) {
+	if c.state == StateOpen && now.Sub(c.openedAt) >= b.options.OpenTimeout {
+		b.transition(key, c, StateHalfOpen, now)
+	}
+}
+
+// transition changes the state of a circuit and notifies the callback
+func (b *Breaker) transition(key string, c *circuit, to BreakerState, now time.Time) {
+	from := c.state
+	c.state = to
+	c.generation++
+	c.trials = 0
+	c.successes = 0
+	if to == StateOpen {
+		c.openedAt = now
+	}
+	if to == StateClosed {
+		c.buckets = [windowBuckets]windowBucket{}
+	}
+
+	if from != to && b.options.OnStateChange != nil {
+		b.changes = append(b.changes, stateChange{key: key, from: from, to: to})
+	}
+}
+
+// unlock releases the lock and reports pending state changes, so the
+// callback may call back into the breaker
+func (b *Breaker) unlock() {
+	changes := b.changes
+	b.changes = nil
+	b.mu.Unlock()
+
+	for _, change := range changes {
+		b.options.OnStateChange(change.key, change.from, change.to)
+	}
+}
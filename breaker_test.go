@@ -0,0 +1,133 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerMiddleware(t *testing.T) {
+	var healthy atomic.Bool
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var changes []string
+	breaker := NewBreaker(&BreakerOptions{
+		MinRequests: 4,
+		FailureRate: 0.5,
+		OpenTimeout: 50 * time.Millisecond,
+		OnStateChange: func(key string, from, to BreakerState) {
+			changes = append(changes, from.String()+"->"+to.String())
+		},
+	})
+	client := NewClient(WithBaseURL(server.URL), WithMiddleware(CircuitBreakerMiddleware(breaker)))
+	key := mustParseURL(t, server.URL).Host
+
+	for i := 0; i < 4; i++ {
+		client.Get("/test", nil)
+	}
+
+	if breaker.State(key) != StateOpen {
+		t.Fatalf("Expected circuit to be open, got %s", breaker.State(key))
+	}
+
+	_, err := client.Get("/test", nil)
+	var openErr *CircuitOpenError
+	if !errors.Is(err, ErrCircuitOpen) || !errors.As(err, &openErr) || openErr.Key != key {
+		t.Errorf("Expected CircuitOpenError for %s, got %v", key, err)
+	}
+	if atomic.LoadInt32(&calls) != 4 {
+		t.Errorf("Expected open circuit to reject without calling upstream, got %d calls", calls)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if breaker.State(key) != StateHalfOpen {
+		t.Fatalf("Expected circuit to be half-open, got %s", breaker.State(key))
+	}
+
+	healthy.Store(true)
+	if _, err := client.Get("/test", nil); err != nil {
+		t.Fatalf("Expected trial request to succeed, got %v", err)
+	}
+
+	if breaker.State(key) != StateClosed {
+		t.Errorf("Expected circuit to be closed, got %s", breaker.State(key))
+	}
+
+	expected := []string{"closed->open", "open->half-open", "half-open->closed"}
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected state changes %v, got %v", expected, changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("Expected state changes %v, got %v", expected, changes)
+		}
+	}
+}
+
+func TestCircuitBreakerMiddleware_HalfOpenFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	breaker := NewBreaker(&BreakerOptions{
+		KeyFunc:     func(req *http.Request) string { return "upstream" },
+		MinRequests: 1,
+		OpenTimeout: 20 * time.Millisecond,
+	})
+	client := NewClient(WithBaseURL(server.URL), WithMiddleware(CircuitBreakerMiddleware(breaker)))
+
+	client.Get("/test", nil)
+	time.Sleep(30 * time.Millisecond)
+	client.Get("/test", nil)
+
+	if breaker.State("upstream") != StateOpen {
+		t.Errorf("Expected failed trial to reopen the circuit, got %s", breaker.State("upstream"))
+	}
+}
+
+func TestCircuitBreakerMiddleware_Cancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	breaker := NewBreaker(&BreakerOptions{
+		KeyFunc:     func(req *http.Request) string { return "upstream" },
+		Window:      5, // shorter than one nanosecond per bucket
+		MinRequests: 1,
+		OpenTimeout: 20 * time.Millisecond,
+	})
+	client := NewClient(WithBaseURL(server.URL), WithMiddleware(CircuitBreakerMiddleware(breaker)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	client.RequestWithContext(ctx, http.MethodGet, "/test", nil)
+	if breaker.State("upstream") != StateClosed {
+		t.Fatalf("Expected a cancelled request not to be recorded, got %s", breaker.State("upstream"))
+	}
+
+	client.Get("/test", nil)
+	time.Sleep(30 * time.Millisecond)
+	client.RequestWithContext(ctx, http.MethodGet, "/test", nil)
+	if breaker.State("upstream") != StateHalfOpen {
+		t.Fatalf("Expected a cancelled trial to keep the circuit half-open, got %s", breaker.State("upstream"))
+	}
+
+	if _, err := client.Get("/test", nil); errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected the trial slot of the cancelled request to be released, got %v", err)
+	}
+}
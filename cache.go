@@ -0,0 +1,497 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheStatus reports how a response was served by CacheMiddleware
+type CacheStatus string
+
+const (
+	// CacheMiss means the response was fetched from the origin
+	CacheMiss CacheStatus = "MISS"
+	// CacheHit means a fresh stored response was served
+	CacheHit CacheStatus = "HIT"
+	// CacheRevalidated means a stored response was confirmed by a 304
+	CacheRevalidated CacheStatus = "REVALIDATED"
+	// CacheStale means a stale stored response was served under
+	// stale-while-revalidate or stale-if-error
+	CacheStale CacheStatus = "STALE"
+)
+
+// GetCacheStatus returns how the response was served by CacheMiddleware,
+// or an empty status if it did not pass through a cache
+func (r *Response) GetCacheStatus() CacheStatus {
+	return r.CacheStatus
+}
+
+// heuristicallyCacheable are the status codes that are cacheable by
+// default (RFC 9110 section 15.1). Only these responses are stored; in
+// particular 206 Partial Content is never stored, as this cache does not
+// combine partial responses.
+var heuristicallyCacheable = map[int]bool{
+	200: true, 203: true, 204: true, 300: true, 301: true,
+	308: true, 404: true, 405: true, 410: true, 414: true, 501: true,
+}
+
+// CacheMiddleware caches GET responses in store following RFC 9111 as a
+// private cache. Unsafe requests invalidate the stored response of their URL.
+func CacheMiddleware(store CacheStore) Middleware {
+	c := &httpCache{store: store}
+
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*Response, error) {
+			if isStreaming(req) {
+				return next(req)
+			}
+			if req.Method != http.MethodGet {
+				resp, err := next(req)
+				if err == nil && !isSafeMethod(req.Method) && resp.StatusCode < 400 {
+					c.invalidate(cacheKey(req))
+				}
+				return resp, err
+			}
+			return c.serve(req, next)
+		}
+	}
+}
+
+// httpCache holds the state shared by the requests of a CacheMiddleware
+type httpCache struct {
+	store        CacheStore
+	revalidating sync.Map
+	mu           sync.Mutex // serializes updates of the primary entries
+}
+
+// serve answers a GET request from the cache or the origin
+func (c *httpCache) serve(req *http.Request, next Handler) (*Response, error) {
+	reqCC := parseCacheControl(req.Header)
+	if reqCC.has("no-store") || req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "" {
+		return next(req)
+	}
+
+	key := cacheKey(req)
+	entry, ok := c.lookup(req, key)
+	if !ok {
+		return c.fetch(req, next, key)
+	}
+
+	respCC := parseCacheControl(entry.Header)
+	now := time.Now()
+	age := entry.age(now)
+	lifetime := entry.freshnessLifetime()
+	mustRevalidate := reqCC.has("no-cache") || respCC.has("no-cache")
+
+	if !mustRevalidate && age < lifetime {
+		if maxAge, ok := reqCC.seconds("max-age"); !ok || age <= maxAge {
+			return entry.response(req, CacheHit, now), nil
+		}
+	}
+
+	staleness := age - lifetime
+	if !mustRevalidate && !respCC.has("must-revalidate") {
+		if window, ok := respCC.seconds("stale-while-revalidate"); ok && staleness >= 0 && staleness <= window {
+			c.revalidateInBackground(req, next, key, entry)
+			return entry.response(req, CacheStale, now), nil
+		}
+	}
+
+	resp, err := c.revalidate(req, next, key, entry)
+	if err == nil && resp.StatusCode < 500 {
+		return resp, nil
+	}
+
+	// A fresh entry reaches this point only when it had to be validated, so
+	// it may not be served in place of an error
+	if staleness >= 0 && !respCC.has("must-revalidate") {
+		window, ok := respCC.seconds("stale-if-error")
+		if !ok {
+			window, ok = reqCC.seconds("stale-if-error")
+		}
+		if ok && staleness <= window {
+			return entry.response(req, CacheStale, time.Now()), nil
+		}
+	}
+
+	return resp, err
+}
+
+// fetch sends req unconditionally and stores the response if allowed
+func (c *httpCache) fetch(req *http.Request, next Handler, key string) (*Response, error) {
+	requestTime := time.Now()
+	resp, err := next(req)
+	if err != nil {
+		return nil, err
+	}
+
+	c.storeResponse(req, resp, key, requestTime)
+	resp.CacheStatus = CacheMiss
+	return resp, nil
+}
+
+// lookup returns the stored response selected by req. The primary entry
+// under key is the latest response; when it has a Vary header, the response
+// for the Vary header values of req is stored under a secondary key.
+func (c *httpCache) lookup(req *http.Request, key string) (*CacheEntry, bool) {
+	entry, ok := c.store.Get(key)
+	if !ok || len(entry.Vary) == 0 {
+		return entry, ok
+	}
+
+	vary := make(map[string]string, len(entry.Vary))
+	for name := range entry.Vary {
+		vary[name] = req.Header.Get(name)
+	}
+	entry, ok = c.store.Get(variantKey(key, vary))
+	if !ok || !entry.matchesVary(req) {
+		return nil, false
+	}
+	return entry, true
+}
+
+// set stores entry under key and, if it has a Vary header, under its
+// secondary key, keeping track of the variants stored for key
+func (c *httpCache) set(key string, entry *CacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var variants []string
+	current := variantKey(key, entry.Vary)
+	if old, ok := c.store.Get(key); ok {
+		for _, variant := range old.Variants {
+			if variant == current {
+				continue
+			}
+			if len(entry.Vary) > 0 && sameVaryNames(old.Vary, entry.Vary) {
+				variants = append(variants, variant)
+			} else {
+				c.store.Delete(variant)
+			}
+		}
+	}
+
+	if len(entry.Vary) == 0 {
+		c.store.Set(key, entry)
+		return
+	}
+
+	c.store.Set(current, entry)
+	primary := *entry
+	primary.Variants = append(variants, current)
+	c.store.Set(key, &primary)
+}
+
+// invalidate drops the responses stored for key and all their variants
+func (c *httpCache) invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, ok := c.store.Get(key); ok {
+		for _, variant := range entry.Variants {
+			c.store.Delete(variant)
+		}
+	}
+	c.store.Delete(key)
+}
+
+// storeResponse stores resp under key, or drops the stored entries if resp
+// may not be stored
+func (c *httpCache) storeResponse(req *http.Request, resp *Response, key string, requestTime time.Time) {
+	if entry := newCacheEntry(req, resp, requestTime); entry != nil {
+		c.set(key, entry)
+	} else {
+		c.invalidate(key)
+	}
+}
+
+// revalidate sends a conditional request for a stored response
+func (c *httpCache) revalidate(req *http.Request, next Handler, key string, entry *CacheEntry) (*Response, error) {
+	etag := entry.Header.Get("ETag")
+	lastModified := entry.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return c.fetch(req, next, key)
+	}
+
+	conditional, err := cloneRequest(req.Context(), req)
+	if err != nil {
+		return nil, err
+	}
+	if etag != "" {
+		conditional.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		conditional.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	requestTime := time.Now()
+	resp, err := next(conditional)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 500 {
+		return resp, nil
+	}
+
+	if resp.StatusCode != http.StatusNotModified {
+		c.storeResponse(req, resp, key, requestTime)
+		resp.CacheStatus = CacheMiss
+		return resp, nil
+	}
+
+	updated := entry.revalidated(resp, requestTime)
+	c.set(key, updated)
+
+	revalidated := updated.response(req, CacheRevalidated, time.Now())
+	revalidated.Timings = resp.Timings
+	return revalidated, nil
+}
+
+// revalidateInBackground revalidates a stale entry once per key without
+// blocking the caller
+func (c *httpCache) revalidateInBackground(req *http.Request, next Handler, key string, entry *CacheEntry) {
+	variant := variantKey(key, entry.Vary)
+	if _, running := c.revalidating.LoadOrStore(variant, true); running {
+		return
+	}
+
+	background := req.Clone(context.WithoutCancel(req.Context()))
+	go func() {
+		defer c.revalidating.Delete(variant)
+		c.revalidate(background, next, key, entry)
+	}()
+}
+
+// newCacheEntry returns the entry to store for resp, or nil if resp may
+// not be stored
+func newCacheEntry(req *http.Request, resp *Response, requestTime time.Time) *CacheEntry {
+	cc := parseCacheControl(resp.Header)
+	if cc.has("no-store") || resp.Header.Get("Vary") == "*" {
+		return nil
+	}
+
+	if !heuristicallyCacheable[resp.StatusCode] {
+		return nil
+	}
+
+	entry := &CacheEntry{
+		StatusCode:   resp.StatusCode,
+		Header:       resp.Header.Clone(),
+		Body:         append([]byte(nil), resp.Body...),
+		RequestTime:  requestTime,
+		ResponseTime: time.Now(),
+		Vary:         make(map[string]string),
+	}
+	for _, name := range varyHeaders(resp.Header) {
+		entry.Vary[name] = req.Header.Get(name)
+	}
+
+	// Without a validator or a freshness lifetime the entry could never be
+	// served or revalidated
+	if entry.Header.Get("ETag") == "" && entry.Header.Get("Last-Modified") == "" && entry.freshnessLifetime() <= 0 {
+		return nil
+	}
+
+	return entry
+}
+
+// matchesVary reports whether req selects the stored response
+func (e *CacheEntry) matchesVary(req *http.Request) bool {
+	for name, value := range e.Vary {
+		if req.Header.Get(name) != value {
+			return false
+		}
+	}
+	return true
+}
+
+// age returns the current age of the entry (RFC 9111 section 4.2.3)
+func (e *CacheEntry) age(now time.Time) time.Duration {
+	apparentAge := time.Duration(0)
+	if date, err := http.ParseTime(e.Header.Get("Date")); err == nil {
+		if d := e.ResponseTime.Sub(date); d > 0 {
+			apparentAge = d
+		}
+	}
+
+	correctedAge := e.ResponseTime.Sub(e.RequestTime)
+	if ageValue, err := strconv.Atoi(e.Header.Get("Age")); err == nil {
+		correctedAge += time.Duration(ageValue) * time.Second
+	}
+
+	initialAge := apparentAge
+	if correctedAge > initialAge {
+		initialAge = correctedAge
+	}
+
+	return initialAge + now.Sub(e.ResponseTime)
+}
+
+// freshnessLifetime returns how long the entry stays fresh (RFC 9111
+// section 4.2.1)
+func (e *CacheEntry) freshnessLifetime() time.Duration {
+	if maxAge, ok := parseCacheControl(e.Header).seconds("max-age"); ok {
+		return maxAge
+	}
+
+	date, err := http.ParseTime(e.Header.Get("Date"))
+	if err != nil {
+		date = e.ResponseTime
+	}
+
+	if expiresHeader := e.Header.Get("Expires"); expiresHeader != "" {
+		expires, err := http.ParseTime(expiresHeader)
+		if err != nil {
+			return 0
+		}
+		return expires.Sub(date)
+	}
+
+	if lastModified, err := http.ParseTime(e.Header.Get("Last-Modified")); err == nil {
+		return date.Sub(lastModified) / 10
+	}
+
+	return 0
+}
+
+// revalidated returns a copy of the entry updated from a 304 response
+func (e *CacheEntry) revalidated(notModified *Response, requestTime time.Time) *CacheEntry {
+	updated := *e
+	updated.Header = e.Header.Clone()
+	for name, values := range notModified.Header {
+		if name == "Content-Length" {
+			continue
+		}
+		updated.Header[name] = values
+	}
+	updated.RequestTime = requestTime
+	updated.ResponseTime = time.Now()
+	return &updated
+}
+
+// response builds a Response from the entry for req
+func (e *CacheEntry) response(req *http.Request, status CacheStatus, now time.Time) *Response {
+	header := e.Header.Clone()
+	header.Set("Age", strconv.Itoa(int(e.age(now).Seconds())))
+
+	return &Response{
+		Response: &http.Response{
+			Status:        strconv.Itoa(e.StatusCode) + " " + http.StatusText(e.StatusCode),
+			StatusCode:    e.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          http.NoBody,
+			ContentLength: int64(len(e.Body)),
+			Request:       req,
+		},
+		Body:        append([]byte(nil), e.Body...),
+		CacheStatus: status,
+	}
+}
+
+// cacheKey returns the primary cache key of req. Only GET responses are
+// stored, so the URL is enough.
+func cacheKey(req *http.Request) string {
+	return req.URL.String()
+}
+
+// variantKey returns the secondary cache key of the response selected by the
+// given Vary header values, or key if there are none
+func variantKey(key string, vary map[string]string) string {
+	if len(vary) == 0 {
+		return key
+	}
+
+	names := make([]string, 0, len(vary))
+	for name := range vary {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString(key)
+	for _, name := range names {
+		b.WriteString("\n" + name + ": " + vary[name])
+	}
+	return b.String()
+}
+
+// sameVaryNames reports whether a and b select responses by the same headers
+func sameVaryNames(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name := range a {
+		if _, ok := b[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// varyHeaders returns the canonical header names listed in Vary
+func varyHeaders(header http.Header) []string {
+	var names []string
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return names
+}
+
+// isSafeMethod reports whether method is safe (RFC 9110 section 9.2.1)
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+// cacheControl holds parsed Cache-Control directives
+type cacheControl map[string]string
+
+// parseCacheControl parses the Cache-Control header
+func parseCacheControl(header http.Header) cacheControl {
+	cc := make(cacheControl)
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name != "" {
+				cc[strings.ToLower(name)] = strings.Trim(arg, `"`)
+			}
+		}
+	}
+	return cc
+}
+
+// has reports whether the directive is present
+func (cc cacheControl) has(name string) bool {
+	_, ok := cc[name]
+	return ok
+}
+
+// seconds returns the delta-seconds argument of a directive
+func (cc cacheControl) seconds(name string) (time.Duration, bool) {
+	arg, ok := cc[name]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return time.Duration(n) * time.Second, true
+}
@@ -0,0 +1,289 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheMiddleware_Hit(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"data": []}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithBaseURL(server.URL), WithMiddleware(CacheMiddleware(NewMemoryCacheStore(10))))
+
+	resp, err := client.Get("/countries", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if resp.GetCacheStatus() != CacheMiss {
+		t.Errorf("Expected first response to be a miss, got %s", resp.GetCacheStatus())
+	}
+
+	resp, err = client.Get("/countries", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if resp.GetCacheStatus() != CacheHit {
+		t.Errorf("Expected second response to be a hit, got %s", resp.GetCacheStatus())
+	}
+	if resp.GetBody() != `{"data": []}` {
+		t.Errorf("Expected cached body, got '%s'", resp.GetBody())
+	}
+
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("Expected 1 upstream call, got %d", calls)
+	}
+
+	client.Post("/countries", nil)
+	resp, _ = client.Get("/countries", nil)
+	if resp.GetCacheStatus() != CacheMiss {
+		t.Errorf("Expected POST to invalidate the cached response, got %s", resp.GetCacheStatus())
+	}
+}
+
+func TestCacheMiddleware_Revalidation(t *testing.T) {
+	var conditional int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&conditional, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"version": 1}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithBaseURL(server.URL), WithMiddleware(CacheMiddleware(NewMemoryCacheStore(10))))
+
+	client.Get("/config", nil)
+	resp, err := client.Get("/config", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if resp.GetCacheStatus() != CacheRevalidated {
+		t.Errorf("Expected response to be revalidated, got %s", resp.GetCacheStatus())
+	}
+	if resp.GetStatusCode() != http.StatusOK || resp.GetBody() != `{"version": 1}` {
+		t.Errorf("Expected stored 200 response, got %d '%s'", resp.GetStatusCode(), resp.GetBody())
+	}
+	if atomic.LoadInt32(&conditional) != 1 {
+		t.Errorf("Expected 1 conditional request, got %d", conditional)
+	}
+}
+
+func TestCacheMiddleware_Vary(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept-Language")
+		w.Write([]byte(r.Header.Get("Accept-Language")))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithBaseURL(server.URL), WithMiddleware(CacheMiddleware(NewMemoryCacheStore(10))))
+	en := &RequestOptions{Headers: map[string]string{"Accept-Language": "en"}}
+	pt := &RequestOptions{Headers: map[string]string{"Accept-Language": "pt"}}
+
+	client.Get("/greeting", en)
+	resp, _ := client.Get("/greeting", en)
+	if resp.GetCacheStatus() != CacheHit {
+		t.Errorf("Expected matching Vary header to hit, got %s", resp.GetCacheStatus())
+	}
+
+	resp, _ = client.Get("/greeting", pt)
+	if resp.GetCacheStatus() != CacheMiss || resp.GetBody() != "pt" {
+		t.Errorf("Expected different Vary header to miss, got %s '%s'", resp.GetCacheStatus(), resp.GetBody())
+	}
+
+	for _, options := range []*RequestOptions{en, pt} {
+		resp, _ = client.Get("/greeting", options)
+		if resp.GetCacheStatus() != CacheHit || resp.GetBody() != options.Headers["Accept-Language"] {
+			t.Errorf("Expected both variants to stay stored, got %s '%s'", resp.GetCacheStatus(), resp.GetBody())
+		}
+	}
+	if atomic.LoadInt32(&calls) != 2 {
+		t.Errorf("Expected 2 calls, got %d", calls)
+	}
+
+	client.Post("/greeting", nil)
+	for _, options := range []*RequestOptions{en, pt} {
+		resp, _ = client.Get("/greeting", options)
+		if resp.GetCacheStatus() != CacheMiss {
+			t.Errorf("Expected unsafe request to invalidate every variant, got %s", resp.GetCacheStatus())
+		}
+	}
+}
+
+func TestCacheMiddleware_NoValidatorNoLifetime(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("now"))
+	}))
+	defer server.Close()
+
+	store := NewMemoryCacheStore(10)
+	client := NewClient(WithBaseURL(server.URL), WithMiddleware(CacheMiddleware(store)))
+	client.Get("/clock", nil)
+
+	if store.Len() != 0 {
+		t.Errorf("Expected response without validator or lifetime not to be stored, got %d entries", store.Len())
+	}
+}
+
+func TestCacheMiddleware_StaleIfError(t *testing.T) {
+	var fail atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Cache-Control", "max-age=60, stale-if-error=600")
+		w.Header().Set("Age", "120")
+		w.Write([]byte("cached"))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithBaseURL(server.URL), WithMiddleware(CacheMiddleware(NewMemoryCacheStore(10))))
+	client.Get("/rates", nil)
+
+	fail.Store(true)
+	resp, err := client.Get("/rates", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if resp.GetCacheStatus() != CacheStale || resp.GetBody() != "cached" {
+		t.Errorf("Expected stale response on error, got %s '%s'", resp.GetCacheStatus(), resp.GetBody())
+	}
+}
+
+func TestCacheMiddleware_StaleIfErrorRequiresStaleEntry(t *testing.T) {
+	var fail atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Cache-Control", "max-age=60, no-cache, stale-if-error=600")
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("cached"))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithBaseURL(server.URL), WithMiddleware(CacheMiddleware(NewMemoryCacheStore(10))))
+	client.Get("/rates", nil)
+
+	fail.Store(true)
+	resp, err := client.Get("/rates", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if resp.GetStatusCode() != http.StatusServiceUnavailable {
+		t.Errorf("Expected the error of a fresh no-cache entry, got %s %d", resp.GetCacheStatus(), resp.GetStatusCode())
+	}
+}
+
+func TestCacheMiddleware_StaleWhileRevalidate(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		w.Header().Set("Cache-Control", "max-age=60, stale-while-revalidate=600")
+		w.Header().Set("Age", "120")
+		w.Write([]byte{byte('0' + n)})
+	}))
+	defer server.Close()
+
+	client := NewClient(WithBaseURL(server.URL), WithMiddleware(CacheMiddleware(NewMemoryCacheStore(10))))
+	client.Get("/rates", nil)
+
+	resp, _ := client.Get("/rates", nil)
+	if resp.GetCacheStatus() != CacheStale || resp.GetBody() != "1" {
+		t.Errorf("Expected stale response while revalidating, got %s '%s'", resp.GetCacheStatus(), resp.GetBody())
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&calls) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if atomic.LoadInt32(&calls) != 2 {
+		t.Errorf("Expected a background revalidation, got %d calls", calls)
+	}
+}
+
+func TestMemoryCacheStore_Eviction(t *testing.T) {
+	store := NewMemoryCacheStore(2)
+	store.Set("a", &CacheEntry{StatusCode: 200})
+	store.Set("b", &CacheEntry{StatusCode: 200})
+	store.Get("a")
+	store.Set("c", &CacheEntry{StatusCode: 200})
+
+	if _, ok := store.Get("b"); ok {
+		t.Error("Expected least recently used entry to be evicted")
+	}
+	if _, ok := store.Get("a"); !ok {
+		t.Error("Expected recently used entry to be kept")
+	}
+	if store.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", store.Len())
+	}
+}
+
+func TestDiskCacheStore(t *testing.T) {
+	store, err := NewDiskCacheStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	store.Set("GET http://example.com/", &CacheEntry{
+		StatusCode: 200,
+		Header:     http.Header{"Etag": {`"v1"`}},
+		Body:       []byte("hello"),
+	})
+
+	entry, ok := store.Get("GET http://example.com/")
+	if !ok {
+		t.Fatal("Expected entry to be stored")
+	}
+	if entry.StatusCode != 200 || string(entry.Body) != "hello" || entry.Header.Get("ETag") != `"v1"` {
+		t.Errorf("Expected stored entry to round-trip, got %+v", entry)
+	}
+
+	store.Delete("GET http://example.com/")
+	if _, ok := store.Get("GET http://example.com/"); ok {
+		t.Error("Expected entry to be deleted")
+	}
+}
+
+func TestCacheMiddleware_UncacheableStatus(t *testing.T) {
+	for _, status := range []int{http.StatusPartialContent, http.StatusInternalServerError} {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.WriteHeader(status)
+			w.Write([]byte("part"))
+		}))
+
+		client := NewClient(WithBaseURL(server.URL), WithMiddleware(CacheMiddleware(NewMemoryCacheStore(10))))
+		client.Get("/video", nil)
+		resp, _ := client.Get("/video", nil)
+		server.Close()
+
+		if resp.GetCacheStatus() != CacheMiss || atomic.LoadInt32(&calls) != 2 {
+			t.Errorf("Expected %d responses not to be stored, got %s after %d calls", status, resp.GetCacheStatus(), calls)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// CoalesceOptions configures a Coalescer
+type CoalesceOptions struct {
+	// Headers are included in the identity of a request in addition to
+	// its method, URL, credentials and body
+	Headers []string
+	// Methods that may be coalesced, defaults to GET and HEAD. Requests
+	// whose body cannot be replayed are never coalesced.
+	Methods []string
+}
+
+// CoalesceStats counts the requests seen by a Coalescer
+type CoalesceStats struct {
+	Requests     uint64 // requests handled by the middleware
+	Upstream     uint64 // requests actually sent
+	Deduplicated uint64 // requests served by another in-flight request
+}
+
+// Coalescer collapses concurrent identical requests into a single
+// upstream call
+type Coalescer struct {
+	mu      sync.Mutex
+	options CoalesceOptions
+	methods map[string]bool
+	calls   map[string]*coalescedCall
+	stats   CoalesceStats
+}
+
+// coalescedCall is an in-flight upstream call shared by its callers
+type coalescedCall struct {
+	done chan struct{}
+	resp *Response
+	err  error
+}
+
+// NewCoalescer creates a new request coalescer
+func NewCoalescer(options *CoalesceOptions) *Coalescer {
+	c := &Coalescer{
+		methods: make(map[string]bool),
+		calls:   make(map[string]*coalescedCall),
+	}
+	if options != nil {
+		c.options = *options
+	}
+	if len(c.options.Methods) == 0 {
+		c.options.Methods = []string{http.MethodGet, http.MethodHead}
+	}
+	for _, method := range c.options.Methods {
+		c.methods[strings.ToUpper(method)] = true
+	}
+	return c
+}
+
+// CoalesceMiddleware shares a single upstream call between concurrent
+// identical requests. Every caller receives its own copy of the response.
+func CoalesceMiddleware(coalescer *Coalescer) Middleware {
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*Response, error) {
+			if !coalescer.methods[req.Method] || isStreaming(req) {
+				return next(req)
+			}
+			return coalescer.do(req, next)
+		}
+	}
+}
+
+// Stats returns a snapshot of the coalescer counters
+func (c *Coalescer) Stats() CoalesceStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// do joins or starts the upstream call for req
+func (c *Coalescer) do(req *http.Request, next Handler) (*Response, error) {
+	key, ok := c.key(req)
+	if !ok {
+		return next(req)
+	}
+
+	c.mu.Lock()
+	c.stats.Requests++
+	call, ok := c.calls[key]
+	if ok {
+		c.stats.Deduplicated++
+	} else {
+		c.stats.Upstream++
+		call = &coalescedCall{done: make(chan struct{})}
+		c.calls[key] = call
+
+		// The shared call must not fail because its first caller gave up
+		shared := req.Clone(context.WithoutCancel(req.Context()))
+		go func() {
+			call.resp, call.err = next(shared)
+
+			c.mu.Lock()
+			delete(c.calls, key)
+			c.mu.Unlock()
+			close(call.done)
+		}()
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+		if call.err != nil {
+			return nil, call.err
+		}
+		return copyResponse(call.resp), nil
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+}
+
+// credentialHeaders are always part of the identity of a request, so that
+// callers never receive a response meant for other credentials
+var credentialHeaders = []string{"Authorization", "Cookie"}
+
+// key returns the identity of req, or false if its body cannot be read
+// without consuming it
+func (c *Coalescer) key(req *http.Request) (string, bool) {
+	var b strings.Builder
+	b.WriteString(req.Method)
+	b.WriteByte(' ')
+	b.WriteString(req.URL.String())
+	for _, name := range append(credentialHeaders[:len(credentialHeaders):len(credentialHeaders)], c.options.Headers...) {
+		b.WriteByte('\n')
+		b.WriteString(http.CanonicalHeaderKey(name))
+		b.WriteString(": ")
+		b.WriteString(strings.Join(req.Header.Values(name), ", "))
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return "", false
+		}
+		body, err := req.GetBody()
+		if err != nil {
+			return "", false
+		}
+		defer body.Close()
+
+		hash := sha256.New()
+		if _, err := io.Copy(hash, body); err != nil {
+			return "", false
+		}
+		b.WriteString("\n\n")
+		b.WriteString(hex.EncodeToString(hash.Sum(nil)))
+	}
+	return b.String(), true
+}
+
+// copyResponse returns a copy of resp that shares no mutable state with it
+func copyResponse(resp *Response) *Response {
+	httpResp := *resp.Response
+	httpResp.Header = resp.Header.Clone()
+	httpResp.Trailer = resp.Trailer.Clone()
+
+	copied := *resp
+	copied.Response = &httpResp
+	copied.Body = append([]byte(nil), resp.Body...)
+	if resp.Timings != nil {
+		timings := *resp.Timings
+		copied.Timings = &timings
+	}
+	return &copied
+}
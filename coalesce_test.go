@@ -0,0 +1,161 @@
+package httpclient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCoalesceMiddleware(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("shared"))
+	}))
+	defer server.Close()
+
+	coalescer := NewCoalescer(nil)
+	client := NewClient(WithBaseURL(server.URL), WithMiddleware(CoalesceMiddleware(coalescer)))
+
+	const callers = 10
+	responses := make([]*Response, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := client.Get("/reference", nil)
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+				return
+			}
+			responses[i] = resp
+		}(i)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for coalescer.Stats().Requests < callers && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("Expected 1 upstream call, got %d", calls)
+	}
+
+	stats := coalescer.Stats()
+	if stats.Upstream != 1 || stats.Deduplicated != callers-1 {
+		t.Errorf("Expected 1 upstream and %d deduplicated requests, got %+v", callers-1, stats)
+	}
+
+	responses[0].Body[0] = 'X'
+	for _, resp := range responses[1:] {
+		if resp.GetBody() != "shared" {
+			t.Errorf("Expected independent response bodies, got '%s'", resp.GetBody())
+		}
+	}
+}
+
+func TestCoalesceMiddleware_Headers(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte(r.Header.Get("Accept-Language")))
+	}))
+	defer server.Close()
+
+	coalescer := NewCoalescer(&CoalesceOptions{Headers: []string{"Accept-Language"}})
+	client := NewClient(WithBaseURL(server.URL), WithMiddleware(CoalesceMiddleware(coalescer)))
+
+	var wg sync.WaitGroup
+	for _, lang := range []string{"en", "pt"} {
+		wg.Add(1)
+		go func(lang string) {
+			defer wg.Done()
+			resp, err := client.Get("/greeting", &RequestOptions{Headers: map[string]string{"Accept-Language": lang}})
+			if err != nil || resp.GetBody() != lang {
+				t.Errorf("Expected body '%s', got %v", lang, err)
+			}
+		}(lang)
+	}
+	wg.Wait()
+
+	if atomic.LoadInt32(&calls) != 2 {
+		t.Errorf("Expected requests with different headers not to be coalesced, got %d calls", calls)
+	}
+}
+
+func TestCoalesceMiddleware_Credentials(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithBaseURL(server.URL), WithMiddleware(CoalesceMiddleware(NewCoalescer(nil))))
+
+	requests := map[string]*RequestOptions{
+		"Bearer alice":           {Headers: map[string]string{"Authorization": "Bearer alice"}},
+		"Bearer bob":             {Headers: map[string]string{"Authorization": "Bearer bob"}},
+		"Basic Y2Fyb2w6c2VjcmV0": {Auth: &Auth{Username: "carol", Password: "secret"}},
+	}
+	var wg sync.WaitGroup
+	for expected, options := range requests {
+		wg.Add(1)
+		go func(expected string, options *RequestOptions) {
+			defer wg.Done()
+			resp, err := client.Get("/me", options)
+			if err != nil || resp.GetBody() != expected {
+				t.Errorf("Expected the response for '%s', got %v", expected, err)
+			}
+		}(expected, options)
+	}
+	wg.Wait()
+
+	if atomic.LoadInt32(&calls) != 3 {
+		t.Errorf("Expected requests with different credentials not to be coalesced, got %d calls", calls)
+	}
+}
+
+func TestCoalesceMiddleware_Body(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	coalescer := NewCoalescer(&CoalesceOptions{Methods: []string{http.MethodPost}})
+	client := NewClient(WithBaseURL(server.URL), WithMiddleware(CoalesceMiddleware(coalescer)))
+
+	var wg sync.WaitGroup
+	for _, query := range []string{"a", "a", "b", "b"} {
+		wg.Add(1)
+		go func(query string) {
+			defer wg.Done()
+			expected := `{"q":"` + query + `"}`
+			resp, err := client.Post("/search", &RequestOptions{JSON: map[string]string{"q": query}})
+			if err != nil || resp.GetBody() != expected {
+				t.Errorf("Expected the response for %s, got %v", expected, err)
+			}
+		}(query)
+	}
+	wg.Wait()
+
+	if atomic.LoadInt32(&calls) != 2 {
+		t.Errorf("Expected only requests with the same body to be coalesced, got %d calls", calls)
+	}
+}
@@ -0,0 +1,204 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ConcurrencyOptions configures a ConcurrencyLimiter
+type ConcurrencyOptions struct {
+	// Limit is the number of requests in flight per key, defaults to 10
+	Limit int
+	// Keys overrides Limit for some keys
+	Keys map[string]int
+	// KeyFunc selects the key of a request, defaults to its host
+	KeyFunc func(*http.Request) string
+	// Collector receives the queue depth and wait time of every key. A
+	// depth that changed again before it was reported is dropped, so the
+	// last reported depth of a key is always its current one.
+	Collector QueueCollector
+}
+
+// ConcurrencyLimiter caps the requests in flight per host or other key.
+// Requests over the cap wait in a first-in first-out queue per key, so a
+// slow host only delays its own requests.
+type ConcurrencyLimiter struct {
+	mu       sync.Mutex
+	options  ConcurrencyOptions
+	keys     map[string]*keySlots
+	depthSeq uint64 // incremented on every queue depth change, under mu
+
+	reportMu sync.Mutex
+	reported map[string]uint64 // depthSeq of the last depth reported per key
+}
+
+// keySlots is the state of a single key
+type keySlots struct {
+	inFlight int
+	waiters  []chan struct{}
+}
+
+// NewConcurrencyLimiter creates a new concurrency limiter
+func NewConcurrencyLimiter(options *ConcurrencyOptions) *ConcurrencyLimiter {
+	l := &ConcurrencyLimiter{
+		keys:     make(map[string]*keySlots),
+		reported: make(map[string]uint64),
+	}
+	if options != nil {
+		l.options = *options
+	}
+	if l.options.Limit <= 0 {
+		l.options.Limit = 10
+	}
+	if l.options.KeyFunc == nil {
+		l.options.KeyFunc = func(req *http.Request) string { return req.URL.Host }
+	}
+	return l
+}
+
+// ConcurrencyLimitMiddleware holds requests until their key has a free
+// slot. Share the limiter between clients, or use it on an AsyncClient, to
+// enforce the caps across all of their calls.
+func ConcurrencyLimitMiddleware(limiter *ConcurrencyLimiter) Middleware {
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*Response, error) {
+			key := limiter.options.KeyFunc(req)
+			if err := limiter.acquire(req.Context(), key); err != nil {
+				return nil, err
+			}
+			defer limiter.release(key)
+
+			return next(req)
+		}
+	}
+}
+
+// InFlight returns the number of requests in flight for key
+func (l *ConcurrencyLimiter) InFlight(key string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if s, ok := l.keys[key]; ok {
+		return s.inFlight
+	}
+	return 0
+}
+
+// Queued returns the number of requests waiting for a slot of key
+func (l *ConcurrencyLimiter) Queued(key string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if s, ok := l.keys[key]; ok {
+		return len(s.waiters)
+	}
+	return 0
+}
+
+// limit returns the cap of key
+func (l *ConcurrencyLimiter) limit(key string) int {
+	if limit, ok := l.options.Keys[key]; ok && limit > 0 {
+		return limit
+	}
+	return l.options.Limit
+}
+
+// acquire takes a slot of key, waiting in line until one is free
+func (l *ConcurrencyLimiter) acquire(ctx context.Context, key string) error {
+	start := time.Now()
+
+	l.mu.Lock()
+	s, ok := l.keys[key]
+	if !ok {
+		s = &keySlots{}
+		l.keys[key] = s
+	}
+	if s.inFlight < l.limit(key) && len(s.waiters) == 0 {
+		s.inFlight++
+		l.mu.Unlock()
+		l.observeWait(key, 0)
+		return nil
+	}
+
+	ready := make(chan struct{})
+	s.waiters = append(s.waiters, ready)
+	depth, seq := len(s.waiters), l.nextDepthSeq()
+	l.mu.Unlock()
+	l.observeDepth(key, depth, seq)
+
+	select {
+	case <-ready:
+		l.observeWait(key, time.Since(start))
+		return nil
+
+	case <-ctx.Done():
+		l.mu.Lock()
+		for i, waiter := range s.waiters {
+			if waiter == ready {
+				s.waiters = append(s.waiters[:i], s.waiters[i+1:]...)
+				depth, seq := len(s.waiters), l.nextDepthSeq()
+				l.mu.Unlock()
+				l.observeDepth(key, depth, seq)
+				return ctx.Err()
+			}
+		}
+		l.mu.Unlock()
+
+		// The slot was handed over while giving up; pass it on
+		l.release(key)
+		return ctx.Err()
+	}
+}
+
+// release frees a slot of key, handing it to the first waiter if any
+func (l *ConcurrencyLimiter) release(key string) {
+	l.mu.Lock()
+	s := l.keys[key]
+	if len(s.waiters) == 0 {
+		s.inFlight--
+		if s.inFlight == 0 {
+			delete(l.keys, key)
+		}
+		l.mu.Unlock()
+		return
+	}
+
+	ready := s.waiters[0]
+	s.waiters[0] = nil
+	s.waiters = s.waiters[1:]
+	close(ready)
+	depth, seq := len(s.waiters), l.nextDepthSeq()
+	l.mu.Unlock()
+	l.observeDepth(key, depth, seq)
+}
+
+// nextDepthSeq numbers a queue depth change; l.mu must be held
+func (l *ConcurrencyLimiter) nextDepthSeq() uint64 {
+	l.depthSeq++
+	return l.depthSeq
+}
+
+// observeDepth reports the queue depth of key taken at seq, unless a later
+// depth was reported already. l.mu must not be held.
+func (l *ConcurrencyLimiter) observeDepth(key string, depth int, seq uint64) {
+	if l.options.Collector == nil {
+		return
+	}
+
+	l.reportMu.Lock()
+	defer l.reportMu.Unlock()
+	if seq < l.reported[key] {
+		return
+	}
+	l.reported[key] = seq
+	l.options.Collector.QueueDepth(key, depth)
+}
+
+// observeWait reports the time a request waited for a slot of key
+func (l *ConcurrencyLimiter) observeWait(key string, wait time.Duration) {
+	if l.options.Collector != nil {
+		l.options.Collector.QueueWait(key, wait)
+	}
+}
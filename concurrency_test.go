@@ -0,0 +1,168 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyLimitMiddleware(t *testing.T) {
+	var slowRunning, slowPeak int32
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&slowRunning, 1)
+		if n > atomic.LoadInt32(&slowPeak) {
+			atomic.StoreInt32(&slowPeak, n)
+		}
+		time.Sleep(100 * time.Millisecond)
+		atomic.AddInt32(&slowRunning, -1)
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer fast.Close()
+
+	metrics := NewMetrics(nil)
+	limiter := NewConcurrencyLimiter(&ConcurrencyOptions{Limit: 2, Collector: metrics})
+	client := NewAsyncClient(WithMiddleware(ConcurrencyLimitMiddleware(limiter)))
+
+	var slowPromises []*Promise
+	for i := 0; i < 6; i++ {
+		slowPromises = append(slowPromises, client.GetAsync(slow.URL, nil))
+	}
+
+	// Requests to another host are not held behind the slow ones
+	start := time.Now()
+	if _, err := client.GetAsync(fast.URL, nil).Wait(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 80*time.Millisecond {
+		t.Errorf("Expected the fast host not to wait, took %v", elapsed)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	slowHost := mustParseURL(t, slow.URL).Host
+	if queued := limiter.Queued(slowHost); queued != 4 {
+		t.Errorf("Expected 4 queued requests, got %d", queued)
+	}
+
+	for _, result := range WaitAll(slowPromises...) {
+		if result.Error != nil {
+			t.Errorf("Expected no error, got %v", result.Error)
+		}
+	}
+	if slowPeak != 2 {
+		t.Errorf("Expected at most 2 requests in flight to the slow host, got %d", slowPeak)
+	}
+	if limiter.InFlight(slowHost) != 0 {
+		t.Errorf("Expected no requests in flight, got %d", limiter.InFlight(slowHost))
+	}
+
+	var buf bytes.Buffer
+	metrics.WritePrometheus(&buf)
+	output := buf.String()
+	for _, expected := range []string{
+		`http_client_queue_depth{key="` + slowHost + `"} 0`,
+		`http_client_queue_wait_seconds_count{key="` + slowHost + `"} 6`,
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", expected, output)
+		}
+	}
+}
+
+func TestConcurrencyLimiter_Cancel(t *testing.T) {
+	limiter := NewConcurrencyLimiter(&ConcurrencyOptions{Limit: 1, Keys: map[string]int{"wide": 3}})
+
+	if err := limiter.acquire(context.Background(), "narrow"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.acquire(ctx, "narrow"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	if limiter.Queued("narrow") != 0 {
+		t.Errorf("Expected the cancelled request to leave the queue, got %d", limiter.Queued("narrow"))
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		limiter.acquire(context.Background(), "narrow")
+		limiter.release("narrow")
+	}()
+	time.Sleep(5 * time.Millisecond)
+	limiter.release("narrow")
+	wg.Wait()
+
+	for i := 0; i < 3; i++ {
+		if err := limiter.acquire(context.Background(), "wide"); err != nil {
+			t.Fatalf("Expected the override to allow 3 requests, got %v", err)
+		}
+	}
+	if limiter.InFlight("narrow") != 0 || limiter.InFlight("wide") != 3 {
+		t.Errorf("Unexpected slots: narrow %d, wide %d", limiter.InFlight("narrow"), limiter.InFlight("wide"))
+	}
+}
+
+// depthRecorder is a QueueCollector recording the reported queue depths
+// and calling back into the limiter
+type depthRecorder struct {
+	mu      sync.Mutex
+	limiter *ConcurrencyLimiter
+	depths  []int
+}
+
+func (r *depthRecorder) QueueDepth(key string, depth int) {
+	r.limiter.Queued(key)
+	runtime.Gosched() // widen the window for reports to overtake each other
+	r.mu.Lock()
+	r.depths = append(r.depths, depth)
+	r.mu.Unlock()
+}
+
+func (r *depthRecorder) QueueWait(key string, wait time.Duration) {}
+
+func TestConcurrencyLimiter_DepthOrder(t *testing.T) {
+	recorder := &depthRecorder{}
+	limiter := NewConcurrencyLimiter(&ConcurrencyOptions{Limit: 1, Collector: recorder})
+	recorder.limiter = limiter
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var wg sync.WaitGroup
+		for i := 0; i < 200; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := limiter.acquire(context.Background(), "host"); err == nil {
+					runtime.Gosched()
+					limiter.release("host")
+				}
+			}()
+		}
+		wg.Wait()
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a collector calling back into the limiter not to deadlock")
+	}
+
+	// Stale depths are dropped, so the last report is the final depth
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if n := len(recorder.depths); n == 0 || recorder.depths[n-1] != 0 {
+		t.Errorf("Expected the last reported depth to be 0, got %v", recorder.depths)
+	}
+}
@@ -0,0 +1,85 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// contextKey is the type of context keys defined by this package
+type contextKey int
+
+const (
+	routeKey contextKey = iota
+	attemptKey
+	spanContextKey
+	redirectHookKey
+	requestIDKey
+)
+
+// WithRoute returns a context carrying the route template of a request
+func WithRoute(ctx context.Context, route string) context.Context {
+	return context.WithValue(ctx, routeKey, route)
+}
+
+// RouteFromContext returns the route template stored in ctx, if any
+func RouteFromContext(ctx context.Context) string {
+	route, _ := ctx.Value(routeKey).(string)
+	return route
+}
+
+// withAttempt returns a context carrying the attempt number of a request
+func withAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptKey, attempt)
+}
+
+// AttemptFromContext returns the attempt number stored in ctx. The first
+// attempt is 1.
+func AttemptFromContext(ctx context.Context) int {
+	if attempt, ok := ctx.Value(attemptKey).(int); ok {
+		return attempt
+	}
+	return 1
+}
+
+// routeOf returns the route template of req, falling back to its URL path
+func routeOf(req *http.Request) string {
+	if route := RouteFromContext(req.Context()); route != "" {
+		return route
+	}
+	return req.URL.Path
+}
+
+// otherRoute labels metrics of requests sent without a route template. URL
+// paths are not used instead as they would give an unbounded number of series.
+const otherRoute = "other"
+
+// routeLabelOf returns the route template of req, or otherRoute if it has none
+func routeLabelOf(req *http.Request) string {
+	if route := RouteFromContext(req.Context()); route != "" {
+		return route
+	}
+	return otherRoute
+}
+
+// withRedirectHook returns a context carrying hook, which is called with
+// every redirected request before it is sent
+func withRedirectHook(ctx context.Context, hook func(*http.Request)) context.Context {
+	hooks, _ := ctx.Value(redirectHookKey).([]func(*http.Request))
+	hooks = append(hooks[:len(hooks):len(hooks)], hook)
+	return context.WithValue(ctx, redirectHookKey, hooks)
+}
+
+// checkRedirect runs the redirect hooks of the request and keeps the
+// default limit of 10 redirects
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+
+	hooks, _ := req.Context().Value(redirectHookKey).([]func(*http.Request))
+	for _, hook := range hooks {
+		hook(req)
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrQueueFull is returned by a dispatcher rejecting work while its
+	// queue is full
+	ErrQueueFull = errors.New("httpclient: dispatcher queue full")
+	// ErrDispatcherClosed is returned for work submitted after Shutdown
+	ErrDispatcherClosed = errors.New("httpclient: dispatcher shut down")
+)
+
+// QueuePolicy selects what happens to work submitted to a full queue
+type QueuePolicy int
+
+const (
+	// QueueBlock waits for room in the queue
+	QueueBlock QueuePolicy = iota
+	// QueueReject fails with ErrQueueFull
+	QueueReject
+)
+
+// Priority is the lane of queued work; higher lanes are served first
+type Priority int
+
+const (
+	// PriorityLow work runs when no other work is queued
+	PriorityLow Priority = -1
+	// PriorityNormal is the default lane
+	PriorityNormal Priority = 0
+	// PriorityHigh work runs before any other queued work
+	PriorityHigh Priority = 1
+)
+
+// DispatcherOptions configures a Dispatcher
+type DispatcherOptions struct {
+	Workers   int // defaults to 10
+	QueueSize int // work waiting for a worker across all lanes, defaults to 100
+	Policy    QueuePolicy
+}
+
+// Dispatcher runs work on a fixed number of workers fed by a bounded,
+// prioritised queue
+type Dispatcher struct {
+	options DispatcherOptions
+	slots   chan struct{} // one per queued job
+	ready   chan struct{} // one per queued job, closed on shutdown
+	closing chan struct{}
+	ctx     context.Context
+	cancel  context.CancelFunc
+	workers sync.WaitGroup
+
+	mu     sync.Mutex
+	lanes  [3][]func(context.Context)
+	closed bool
+}
+
+// NewDispatcher creates a dispatcher and starts its workers
+func NewDispatcher(options *DispatcherOptions) *Dispatcher {
+	d := &Dispatcher{closing: make(chan struct{})}
+	if options != nil {
+		d.options = *options
+	}
+	if d.options.Workers <= 0 {
+		d.options.Workers = 10
+	}
+	if d.options.QueueSize <= 0 {
+		d.options.QueueSize = 100
+	}
+	d.slots = make(chan struct{}, d.options.QueueSize)
+	d.ready = make(chan struct{}, d.options.QueueSize)
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+
+	d.workers.Add(d.options.Workers)
+	for i := 0; i < d.options.Workers; i++ {
+		go d.work()
+	}
+	return d
+}
+
+// WithDispatcher runs the requests of AsyncClient.SendAsync on d instead
+// of a goroutine each
+func WithDispatcher(d *Dispatcher) ClientOption {
+	return func(c *Client) {
+		c.dispatcher = d
+	}
+}
+
+// Submit queues job in the lane of priority. The job receives a context
+// cancelled when Shutdown gives up waiting. With QueueBlock, Submit waits
+// for room in the queue until ctx is done.
+func (d *Dispatcher) Submit(ctx context.Context, priority Priority, job func(ctx context.Context)) error {
+	if d.options.Policy == QueueReject {
+		select {
+		case d.slots <- struct{}{}:
+		default:
+			return ErrQueueFull
+		}
+	} else {
+		select {
+		case d.slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-d.closing:
+			return ErrDispatcherClosed
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		<-d.slots
+		return ErrDispatcherClosed
+	}
+	lane := laneOf(priority)
+	d.lanes[lane] = append(d.lanes[lane], job)
+	d.ready <- struct{}{}
+	return nil
+}
+
+// Queued returns the number of jobs waiting for a worker
+func (d *Dispatcher) Queued() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.lanes[0]) + len(d.lanes[1]) + len(d.lanes[2])
+}
+
+// Shutdown stops accepting work and waits for the queued and running jobs
+// to finish. When ctx is done first, it cancels the context of the jobs,
+// so the remaining ones fail fast, and returns ctx.Err().
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.closing)
+		close(d.ready)
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		d.cancel()
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		return ctx.Err()
+	}
+}
+
+// work runs queued jobs until the dispatcher is shut down and drained
+func (d *Dispatcher) work() {
+	defer d.workers.Done()
+
+	for range d.ready {
+		job := d.pop()
+		<-d.slots
+		job(d.ctx)
+	}
+}
+
+// pop removes the next job from the highest non-empty lane
+func (d *Dispatcher) pop() func(context.Context) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for lane := len(d.lanes) - 1; lane >= 0; lane-- {
+		if len(d.lanes[lane]) > 0 {
+			job := d.lanes[lane][0]
+			d.lanes[lane][0] = nil
+			d.lanes[lane] = d.lanes[lane][1:]
+			return job
+		}
+	}
+	return nil
+}
+
+// laneOf returns the lane index of priority
+func laneOf(priority Priority) int {
+	switch {
+	case priority < PriorityNormal:
+		return 0
+	case priority > PriorityNormal:
+		return 2
+	default:
+		return 1
+	}
+}
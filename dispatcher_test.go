@@ -0,0 +1,215 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDispatcher_Workers(t *testing.T) {
+	d := NewDispatcher(&DispatcherOptions{Workers: 2, QueueSize: 20})
+	var running, peak int32
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		err := d.Submit(context.Background(), PriorityNormal, func(ctx context.Context) {
+			defer wg.Done()
+			n := atomic.AddInt32(&running, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	wg.Wait()
+
+	if peak != 2 {
+		t.Errorf("Expected 2 jobs at most to run at once, got %d", peak)
+	}
+	if err := d.Shutdown(context.Background()); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+func TestDispatcher_Policies(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	block := func(ctx context.Context) {
+		started <- struct{}{}
+		<-release
+	}
+	noop := func(ctx context.Context) {}
+
+	d := NewDispatcher(&DispatcherOptions{Workers: 1, QueueSize: 1, Policy: QueueReject})
+	d.Submit(context.Background(), PriorityNormal, block)
+	<-started
+	if err := d.Submit(context.Background(), PriorityNormal, noop); err != nil {
+		t.Errorf("Expected the job to be queued, got %v", err)
+	}
+	if err := d.Submit(context.Background(), PriorityNormal, noop); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected ErrQueueFull, got %v", err)
+	}
+	close(release)
+	d.Shutdown(context.Background())
+
+	release = make(chan struct{})
+	d = NewDispatcher(&DispatcherOptions{Workers: 1, QueueSize: 1, Policy: QueueBlock})
+	d.Submit(context.Background(), PriorityNormal, block)
+	<-started
+	d.Submit(context.Background(), PriorityNormal, noop)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := d.Submit(ctx, PriorityNormal, noop); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected a blocked submit to end with its context, got %v", err)
+	}
+	close(release)
+	d.Shutdown(context.Background())
+}
+
+func TestDispatcher_Priority(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	d := NewDispatcher(&DispatcherOptions{Workers: 1})
+	d.Submit(context.Background(), PriorityNormal, func(ctx context.Context) {
+		close(started)
+		<-release
+	})
+	<-started
+
+	var order []string
+	for _, job := range []struct {
+		name     string
+		priority Priority
+	}{{"low", PriorityLow}, {"normal", PriorityNormal}, {"high", PriorityHigh}} {
+		name := job.name
+		d.Submit(context.Background(), job.priority, func(ctx context.Context) {
+			order = append(order, name)
+		})
+	}
+	if d.Queued() != 3 {
+		t.Errorf("Expected 3 queued jobs, got %d", d.Queued())
+	}
+	close(release)
+	d.Shutdown(context.Background())
+
+	if len(order) != 3 || order[0] != "high" || order[1] != "normal" || order[2] != "low" {
+		t.Errorf("Expected jobs by priority, got %v", order)
+	}
+}
+
+func TestDispatcher_Shutdown(t *testing.T) {
+	d := NewDispatcher(&DispatcherOptions{Workers: 1})
+	var ran int32
+	for i := 0; i < 5; i++ {
+		d.Submit(context.Background(), PriorityNormal, func(ctx context.Context) {
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&ran, 1)
+		})
+	}
+	if err := d.Shutdown(context.Background()); err != nil {
+		t.Errorf("Expected a drained shutdown, got %v", err)
+	}
+	if ran != 5 {
+		t.Errorf("Expected queued jobs to be drained, got %d", ran)
+	}
+	if err := d.Submit(context.Background(), PriorityNormal, func(ctx context.Context) {}); !errors.Is(err, ErrDispatcherClosed) {
+		t.Errorf("Expected ErrDispatcherClosed, got %v", err)
+	}
+
+	d = NewDispatcher(&DispatcherOptions{Workers: 1})
+	cancelled := make(chan struct{})
+	d.Submit(context.Background(), PriorityNormal, func(ctx context.Context) {
+		<-ctx.Done()
+		close(cancelled)
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := d.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("Expected running jobs to be cancelled")
+	}
+}
+
+func TestAsyncClient_Dispatcher(t *testing.T) {
+	var running, peak int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&running, 1)
+		if n > atomic.LoadInt32(&peak) {
+			atomic.StoreInt32(&peak, n)
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+	}))
+	defer server.Close()
+
+	d := NewDispatcher(&DispatcherOptions{Workers: 2, QueueSize: 2, Policy: QueueReject})
+	defer d.Shutdown(context.Background())
+	client := NewAsyncClient(WithBaseURL(server.URL), WithDispatcher(d))
+
+	var promises []*Promise
+	for i := 0; i < 5; i++ {
+		promises = append(promises, client.GetAsync("/test", nil))
+	}
+
+	rejected := 0
+	for _, result := range WaitAll(promises...) {
+		if errors.Is(result.Error, ErrQueueFull) {
+			rejected++
+		} else if result.Error != nil {
+			t.Errorf("Expected no error, got %v", result.Error)
+		}
+	}
+	if rejected == 0 {
+		t.Error("Expected requests beyond the queue to be rejected")
+	}
+	if peak > 2 {
+		t.Errorf("Expected at most 2 concurrent requests, got %d", peak)
+	}
+}
+
+func TestAsyncClient_SendConcurrentWithLimit(t *testing.T) {
+	var running, peak int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&running, 1)
+		if n > atomic.LoadInt32(&peak) {
+			atomic.StoreInt32(&peak, n)
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		w.Write([]byte(r.URL.Path))
+	}))
+	defer server.Close()
+
+	client := NewAsyncClient(WithBaseURL(server.URL))
+	requests := []ConcurrentRequest{
+		{Method: "GET", Path: "/a"}, {Method: "GET", Path: "/b"}, {Method: "GET", Path: "/c"}, {Method: "GET", Path: "/d"},
+	}
+	results := client.SendConcurrentWithLimit(requests, 2)
+
+	for i, result := range results {
+		if result.Error != nil || result.Index != i || result.Response.GetBody() != requests[i].Path {
+			t.Errorf("Unexpected result %d: %+v", i, result)
+		}
+	}
+	if peak > 2 {
+		t.Errorf("Expected at most 2 concurrent requests, got %d", peak)
+	}
+}
@@ -0,0 +1,430 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoEndpoints is returned when a client balancing between endpoints has
+// none to send a request to
+var ErrNoEndpoints = errors.New("httpclient: no endpoints available")
+
+// Strategy selects the endpoint serving a request
+type Strategy int
+
+const (
+	// RoundRobin cycles through the healthy endpoints
+	RoundRobin Strategy = iota
+	// Random picks a healthy endpoint at random
+	Random
+	// LeastInFlight picks the healthy endpoint with the fewest requests in flight
+	LeastInFlight
+	// Weighted distributes requests in proportion to endpoint weights
+	Weighted
+)
+
+// Endpoint is a base URL a client can send requests to
+type Endpoint struct {
+	URL      string
+	Weight   int // relative share of requests for the Weighted strategy, defaults to 1
+	Priority int // lower priorities are used first; higher ones only when those are ejected
+}
+
+// EndpointStatus describes the current state of an endpoint
+type EndpointStatus struct {
+	URL      string
+	Healthy  bool
+	InFlight int
+}
+
+// HealthCheckOptions configures endpoint ejection and probing
+type HealthCheckOptions struct {
+	// FailureThreshold is the number of consecutive failures that ejects
+	// an endpoint, defaults to 3
+	FailureThreshold int
+	// Path is probed with GET on ejected endpoints; a 2xx response brings
+	// them back. Without a path, endpoints come back after Interval.
+	Path string
+	// Interval between probes of an ejected endpoint, defaults to 10s
+	Interval time.Duration
+}
+
+// WithEndpoints balances requests between several base URLs. Failed
+// idempotent requests fail over to the next endpoint, unless their body is
+// a reader that cannot be read again.
+func WithEndpoints(urls []string, strategy Strategy) ClientOption {
+	return func(c *Client) {
+		c.balancing.endpoints = make([]Endpoint, len(urls))
+		for i, u := range urls {
+			c.balancing.endpoints[i] = Endpoint{URL: u, Weight: 1}
+		}
+		c.balancing.strategy = strategy
+	}
+}
+
+// WithWeightedEndpoints balances requests between endpoints in proportion
+// to their weights
+func WithWeightedEndpoints(endpoints []Endpoint) ClientOption {
+	return func(c *Client) {
+		c.balancing.endpoints = append([]Endpoint(nil), endpoints...)
+		c.balancing.strategy = Weighted
+	}
+}
+
+// WithHealthCheck configures passive ejection and active probing of endpoints
+func WithHealthCheck(options HealthCheckOptions) ClientOption {
+	return func(c *Client) {
+		c.balancing.health = options
+	}
+}
+
+// Endpoints returns the status of the endpoints the client balances between
+func (c *Client) Endpoints() []EndpointStatus {
+	if c.pool == nil {
+		return nil
+	}
+	return c.pool.status()
+}
+
+// balancerConfig holds the endpoint settings collected from client options
+type balancerConfig struct {
+	endpoints []Endpoint
+	source    endpointSource
+	strategy  Strategy
+	health    HealthCheckOptions
+}
+
+// endpointSource resolves the endpoints of a pool and how long they stay valid
+type endpointSource func(ctx context.Context) ([]Endpoint, time.Duration, error)
+
+// endpointPool balances requests between endpoints and tracks their health
+type endpointPool struct {
+	mu         sync.Mutex
+	strategy   Strategy
+	health     HealthCheckOptions
+	httpClient *http.Client
+	endpoints  []*endpoint
+	next       int
+
+	source    endpointSource
+	refreshAt time.Time
+	resolving chan struct{}
+	sourceErr error
+}
+
+// endpoint is the state of a single base URL
+type endpoint struct {
+	base      *url.URL
+	weight    int
+	priority  int
+	current   int // smooth weighted round-robin state
+	inFlight  int
+	failures  int
+	ejected   bool
+	nextProbe time.Time
+	probing   bool
+}
+
+// newEndpointPool creates a pool from the client configuration
+func newEndpointPool(config balancerConfig, httpClient *http.Client) *endpointPool {
+	p := &endpointPool{
+		strategy:   config.strategy,
+		health:     config.health,
+		httpClient: httpClient,
+		source:     config.source,
+	}
+	if p.health.FailureThreshold <= 0 {
+		p.health.FailureThreshold = 3
+	}
+	if p.health.Interval <= 0 {
+		p.health.Interval = 10 * time.Second
+	}
+	p.setEndpoints(config.endpoints)
+	return p
+}
+
+// setEndpoints replaces the endpoints of the pool, keeping the state of
+// those that remain
+func (p *endpointPool) setEndpoints(endpoints []Endpoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	existing := make(map[string]*endpoint)
+	for _, ep := range p.endpoints {
+		existing[ep.base.String()] = ep
+	}
+
+	p.endpoints = p.endpoints[:0:0]
+	for _, e := range endpoints {
+		base, err := url.Parse(strings.TrimRight(e.URL, "/"))
+		if err != nil || base.Host == "" {
+			continue
+		}
+		weight := e.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+
+		ep, ok := existing[base.String()]
+		if !ok {
+			ep = &endpoint{base: base}
+		}
+		ep.weight = weight
+		ep.priority = e.Priority
+		p.endpoints = append(p.endpoints, ep)
+	}
+}
+
+// do sends req to an endpoint, failing over to the others for
+// idempotent requests whose body can be sent again
+func (p *endpointPool) do(req *http.Request, handler Handler) (*Response, error) {
+	if p.source != nil {
+		if err := p.refresh(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+
+	tried := make(map[*endpoint]bool)
+	var lastResp *Response
+	lastErr := ErrNoEndpoints
+
+	for {
+		ep := p.pick(tried)
+		if ep == nil {
+			return lastResp, lastErr
+		}
+		tried[ep] = true
+
+		attemptReq, err := cloneRequest(req.Context(), req)
+		if err != nil {
+			return nil, err
+		}
+		attemptReq.URL = ep.resolve(req.URL)
+		attemptReq.Host = ""
+
+		resp, err := handler(attemptReq)
+		failed := err != nil || resp.StatusCode == http.StatusBadGateway ||
+			resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == http.StatusGatewayTimeout
+		p.done(ep, failed && !errors.Is(err, context.Canceled))
+
+		if !failed || !isIdempotentMethod(req.Method) || !canResend(req) || req.Context().Err() != nil {
+			return resp, err
+		}
+		lastResp, lastErr = resp, err
+	}
+}
+
+// pick selects an endpoint not yet tried and counts it as in flight
+func (p *endpointPool) pick(tried map[*endpoint]bool) *endpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	var healthy, ejected []*endpoint
+	for _, ep := range p.endpoints {
+		if ep.ejected && !ep.probing && !now.Before(ep.nextProbe) {
+			p.probe(ep)
+		}
+		if tried[ep] {
+			continue
+		}
+		if ep.ejected {
+			ejected = append(ejected, ep)
+		} else {
+			healthy = append(healthy, ep)
+		}
+	}
+
+	// When every endpoint is ejected, keep trying them rather than fail
+	candidates := lowestPriority(healthy)
+	if len(candidates) == 0 {
+		candidates = lowestPriority(ejected)
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	var ep *endpoint
+	switch p.strategy {
+	case Random:
+		ep = candidates[rand.Intn(len(candidates))]
+	case LeastInFlight:
+		offset := p.next
+		p.next++
+		for i := range candidates {
+			c := candidates[(offset+i)%len(candidates)]
+			if ep == nil || c.inFlight < ep.inFlight {
+				ep = c
+			}
+		}
+	case Weighted:
+		total := 0
+		for _, c := range candidates {
+			c.current += c.weight
+			total += c.weight
+			if ep == nil || c.current > ep.current {
+				ep = c
+			}
+		}
+		ep.current -= total
+	default:
+		ep = candidates[p.next%len(candidates)]
+		p.next++
+	}
+
+	ep.inFlight++
+	return ep
+}
+
+// lowestPriority returns the endpoints sharing the lowest priority
+func lowestPriority(endpoints []*endpoint) []*endpoint {
+	var lowest []*endpoint
+	for _, ep := range endpoints {
+		if len(lowest) > 0 && ep.priority > lowest[0].priority {
+			continue
+		}
+		if len(lowest) > 0 && ep.priority < lowest[0].priority {
+			lowest = lowest[:0]
+		}
+		lowest = append(lowest, ep)
+	}
+	return lowest
+}
+
+// refresh resolves the endpoints from the source once they expire.
+// Requests only wait for the resolution while no endpoints are known;
+// otherwise the previous endpoints keep serving.
+func (p *endpointPool) refresh(ctx context.Context) error {
+	p.mu.Lock()
+	if p.resolving == nil && !time.Now().Before(p.refreshAt) {
+		p.resolving = make(chan struct{})
+		go p.resolve(p.resolving)
+	}
+	resolving := p.resolving
+	empty := len(p.endpoints) == 0
+	p.mu.Unlock()
+
+	if !empty {
+		return nil
+	}
+	if resolving != nil {
+		select {
+		case <-resolving:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.endpoints) == 0 && p.sourceErr != nil {
+		return p.sourceErr
+	}
+	return nil
+}
+
+// resolve queries the source and updates the endpoints
+func (p *endpointPool) resolve(done chan struct{}) {
+	defer close(done)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	endpoints, ttl, err := p.source(ctx)
+	if err == nil {
+		p.setEndpoints(endpoints)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil || ttl <= 0 {
+		ttl = 5 * time.Second
+	}
+	p.refreshAt = time.Now().Add(ttl)
+	p.sourceErr = err
+	p.resolving = nil
+}
+
+// done records the outcome of a request sent to ep
+func (p *endpointPool) done(ep *endpoint, failed bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	ep.inFlight--
+	if !failed {
+		ep.failures = 0
+		return
+	}
+
+	ep.failures++
+	if !ep.ejected && ep.failures >= p.health.FailureThreshold {
+		ep.ejected = true
+		ep.nextProbe = time.Now().Add(p.health.Interval)
+	}
+}
+
+// probe checks an ejected endpoint in the background. Must be called with
+// the lock held.
+func (p *endpointPool) probe(ep *endpoint) {
+	if p.health.Path == "" {
+		ep.ejected = false
+		ep.failures = 0
+		return
+	}
+
+	ep.probing = true
+	probeURL := ep.resolve(&url.URL{Path: p.health.Path})
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), p.health.Interval)
+		defer cancel()
+
+		healthy := false
+		if req, err := http.NewRequestWithContext(ctx, http.MethodGet, probeURL.String(), nil); err == nil {
+			if resp, err := p.httpClient.Do(req); err == nil {
+				resp.Body.Close()
+				healthy = resp.StatusCode >= 200 && resp.StatusCode < 300
+			}
+		}
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		ep.probing = false
+		if healthy {
+			ep.ejected = false
+			ep.failures = 0
+		} else {
+			ep.nextProbe = time.Now().Add(p.health.Interval)
+		}
+	}()
+}
+
+// status returns a snapshot of the endpoints
+func (p *endpointPool) status() []EndpointStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	statuses := make([]EndpointStatus, len(p.endpoints))
+	for i, ep := range p.endpoints {
+		statuses[i] = EndpointStatus{
+			URL:      ep.base.String(),
+			Healthy:  !ep.ejected,
+			InFlight: ep.inFlight,
+		}
+	}
+	return statuses
+}
+
+// resolve joins a request URL onto the endpoint base URL
+func (ep *endpoint) resolve(ref *url.URL) *url.URL {
+	u := *ep.base
+	u.Path = strings.TrimRight(ep.base.Path, "/") + "/" + strings.TrimLeft(ref.Path, "/")
+	u.RawPath = ""
+	u.RawQuery = ref.RawQuery
+	return &u
+}
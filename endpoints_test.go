@@ -0,0 +1,173 @@
+package httpclient
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newCountingServer(calls *int32, status int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		w.WriteHeader(status)
+		w.Write([]byte(r.URL.Path))
+	}))
+}
+
+func TestClient_EndpointsRoundRobin(t *testing.T) {
+	var callsA, callsB int32
+	serverA := newCountingServer(&callsA, http.StatusOK)
+	defer serverA.Close()
+	serverB := newCountingServer(&callsB, http.StatusOK)
+	defer serverB.Close()
+
+	client := NewClient(WithEndpoints([]string{serverA.URL + "/api", serverB.URL + "/api"}, RoundRobin))
+
+	for i := 0; i < 4; i++ {
+		resp, err := client.Get("/users", nil)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if resp.GetBody() != "/api/users" {
+			t.Errorf("Expected path '/api/users', got '%s'", resp.GetBody())
+		}
+	}
+
+	if callsA != 2 || callsB != 2 {
+		t.Errorf("Expected 2 calls per endpoint, got %d and %d", callsA, callsB)
+	}
+}
+
+func TestClient_EndpointsFailover(t *testing.T) {
+	var calls int32
+	healthy := newCountingServer(&calls, http.StatusOK)
+	defer healthy.Close()
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	client := NewClient(
+		WithEndpoints([]string{dead.URL, healthy.URL}, RoundRobin),
+		WithHealthCheck(HealthCheckOptions{FailureThreshold: 1, Interval: time.Hour}),
+	)
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.Get("/users", nil); err != nil {
+			t.Fatalf("Expected failover to the healthy endpoint, got %v", err)
+		}
+	}
+
+	statuses := client.Endpoints()
+	if statuses[0].Healthy || !statuses[1].Healthy {
+		t.Errorf("Expected dead endpoint to be ejected, got %+v", statuses)
+	}
+
+	if _, err := client.Post("/users", nil); err != nil {
+		t.Errorf("Expected POST to go to the healthy endpoint, got %v", err)
+	}
+}
+
+func TestClient_EndpointsNoFailoverForPost(t *testing.T) {
+	var calls int32
+	unavailable := newCountingServer(&calls, http.StatusServiceUnavailable)
+	defer unavailable.Close()
+	healthy := newCountingServer(&calls, http.StatusOK)
+	defer healthy.Close()
+
+	client := NewClient(WithEndpoints([]string{unavailable.URL, healthy.URL}, RoundRobin))
+
+	resp, err := client.Post("/orders", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if resp.GetStatusCode() != http.StatusServiceUnavailable || calls != 1 {
+		t.Errorf("Expected POST not to fail over, got status %d after %d calls", resp.GetStatusCode(), calls)
+	}
+}
+
+func TestClient_EndpointsNoFailoverForStreamedBody(t *testing.T) {
+	var calls int32
+	unavailable := newCountingServer(&calls, http.StatusServiceUnavailable)
+	defer unavailable.Close()
+	healthy := newCountingServer(&calls, http.StatusOK)
+	defer healthy.Close()
+
+	client := NewClient(WithEndpoints([]string{unavailable.URL, healthy.URL}, RoundRobin))
+
+	// A reader without GetBody cannot be sent to a second endpoint
+	resp, err := client.Put("/files/1", &RequestOptions{Body: io.MultiReader(strings.NewReader("data"))})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if resp.GetStatusCode() != http.StatusServiceUnavailable || calls != 1 {
+		t.Errorf("Expected PUT with a streamed body not to fail over, got status %d after %d calls", resp.GetStatusCode(), calls)
+	}
+}
+
+func TestClient_EndpointsHealthProbe(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !failing.Load() {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient(
+		WithEndpoints([]string{server.URL}, RoundRobin),
+		WithHealthCheck(HealthCheckOptions{FailureThreshold: 1, Path: "/healthz", Interval: 10 * time.Millisecond}),
+	)
+
+	client.Get("/users", nil)
+	if client.Endpoints()[0].Healthy {
+		t.Fatal("Expected endpoint to be ejected")
+	}
+
+	failing.Store(false)
+	deadline := time.Now().Add(time.Second)
+	for !client.Endpoints()[0].Healthy && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		client.Get("/users", nil)
+	}
+
+	if !client.Endpoints()[0].Healthy {
+		t.Error("Expected endpoint to be readmitted after a successful probe")
+	}
+}
+
+func TestClient_EndpointsWeighted(t *testing.T) {
+	var callsA, callsB int32
+	serverA := newCountingServer(&callsA, http.StatusOK)
+	defer serverA.Close()
+	serverB := newCountingServer(&callsB, http.StatusOK)
+	defer serverB.Close()
+
+	client := NewClient(WithWeightedEndpoints([]Endpoint{
+		{URL: serverA.URL, Weight: 3},
+		{URL: serverB.URL, Weight: 1},
+	}))
+
+	for i := 0; i < 8; i++ {
+		client.Get("/users", nil)
+	}
+
+	if callsA != 6 || callsB != 2 {
+		t.Errorf("Expected a 3:1 split, got %d and %d", callsA, callsB)
+	}
+}
+
+func TestClient_EndpointsNone(t *testing.T) {
+	client := NewClient()
+	client.pool = newEndpointPool(balancerConfig{}, client.httpClient)
+
+	if _, err := client.Get("/users", nil); !errors.Is(err, ErrNoEndpoints) {
+		t.Errorf("Expected ErrNoEndpoints, got %v", err)
+	}
+}
@@ -0,0 +1,234 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInjectedFault is matched by errors.Is for every FaultError
+var ErrInjectedFault = errors.New("httpclient: injected fault")
+
+// FaultError is returned for injected connection errors and timeouts
+type FaultError struct {
+	Kind string // "connection" or "timeout"
+}
+
+// Error implements error
+func (e *FaultError) Error() string {
+	return "httpclient: injected " + e.Kind + " fault"
+}
+
+// Timeout reports whether the fault is a timeout, like net.Error
+func (e *FaultError) Timeout() bool {
+	return e.Kind == "timeout"
+}
+
+// Is makes errors.Is match ErrInjectedFault, and context.DeadlineExceeded
+// for timeouts
+func (e *FaultError) Is(target error) bool {
+	return target == ErrInjectedFault || (e.Timeout() && target == context.DeadlineExceeded)
+}
+
+// Faults are the probabilities, between 0 and 1, of each fault being
+// injected into a request
+type Faults struct {
+	LatencyRate float64
+	Latency     time.Duration // added before the request is sent
+
+	ErrorRate float64 // connection error instead of sending the request
+
+	TimeoutRate float64
+	// TimeoutAfter is how long a timeout fault hangs when the request has
+	// no deadline, defaults to 30s
+	TimeoutAfter time.Duration
+
+	StatusRate  float64
+	StatusCodes []int // response status picked at random, defaults to 503
+
+	TruncateRate float64 // response body cut in half
+}
+
+// FaultOptions configures FaultInjectionMiddleware
+type FaultOptions struct {
+	// Enabled switches injection on; the middleware passes requests
+	// through untouched otherwise
+	Enabled bool
+	Default Faults
+	Routes  map[string]Faults // per route template, replacing Default
+	// Seed makes the injected faults deterministic; zero seeds from the clock
+	Seed int64
+}
+
+// FaultInjectionMiddleware injects latency, connection errors, timeouts,
+// error statuses and truncated bodies into requests for resilience
+// testing. Place it inside RetryMiddleware and CircuitBreakerMiddleware so
+// they see the faults.
+func FaultInjectionMiddleware(options *FaultOptions) Middleware {
+	if options == nil || !options.Enabled {
+		return func(next Handler) Handler { return next }
+	}
+	seed := options.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	f := &faultInjector{options: options, rand: rand.New(rand.NewSource(seed))}
+
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*Response, error) {
+			return f.do(req, next)
+		}
+	}
+}
+
+// faultInjector holds the random source of a FaultInjectionMiddleware
+type faultInjector struct {
+	options *FaultOptions
+	mu      sync.Mutex
+	rand    *rand.Rand
+}
+
+// faultPlan is the set of faults drawn for a request
+type faultPlan struct {
+	latency  bool
+	err      bool
+	timeout  bool
+	status   int
+	truncate bool
+}
+
+// do injects the faults drawn for req
+func (f *faultInjector) do(req *http.Request, next Handler) (*Response, error) {
+	faults, ok := f.options.Routes[routeOf(req)]
+	if !ok {
+		faults = f.options.Default
+	}
+	plan := f.draw(faults)
+
+	if plan.latency {
+		if err := sleepContext(req.Context(), faults.Latency); err != nil {
+			return nil, err
+		}
+	}
+	if plan.timeout {
+		timeout := faults.TimeoutAfter
+		if timeout <= 0 {
+			timeout = 30 * time.Second
+		}
+		if err := sleepContext(req.Context(), timeout); err != nil {
+			return nil, err
+		}
+		return nil, &FaultError{Kind: "timeout"}
+	}
+	if plan.err {
+		return nil, &FaultError{Kind: "connection"}
+	}
+	if plan.status != 0 {
+		return &Response{Response: &http.Response{
+			Status:     fmt.Sprintf("%d %s", plan.status, http.StatusText(plan.status)),
+			StatusCode: plan.status,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     make(http.Header),
+			Body:       http.NoBody,
+			Request:    req,
+		}}, nil
+	}
+
+	resp, err := next(req)
+	if err == nil && plan.truncate {
+		resp.Body = resp.Body[:len(resp.Body)/2]
+	}
+	return resp, err
+}
+
+// draw rolls every fault of faults
+func (f *faultInjector) draw(faults Faults) faultPlan {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	plan := faultPlan{
+		latency:  f.rand.Float64() < faults.LatencyRate,
+		timeout:  f.rand.Float64() < faults.TimeoutRate,
+		err:      f.rand.Float64() < faults.ErrorRate,
+		truncate: f.rand.Float64() < faults.TruncateRate,
+	}
+	if f.rand.Float64() < faults.StatusRate {
+		plan.status = http.StatusServiceUnavailable
+		if len(faults.StatusCodes) > 0 {
+			plan.status = faults.StatusCodes[f.rand.Intn(len(faults.StatusCodes))]
+		}
+	}
+	return plan
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ParseFaults parses faults from a comma-separated configuration string
+// such as "latency=0.1:200ms,error=0.05,timeout=0.01:5s,status=0.1:502|503,
+// truncate=0.02", suitable for an environment variable
+func ParseFaults(spec string) (Faults, error) {
+	var faults Faults
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(field, "=")
+		rateValue, arg, hasArg := strings.Cut(value, ":")
+		rate, err := strconv.ParseFloat(rateValue, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return Faults{}, fmt.Errorf("httpclient: invalid fault rate %q", field)
+		}
+
+		switch strings.TrimSpace(name) {
+		case "latency":
+			faults.LatencyRate = rate
+			if faults.Latency, err = time.ParseDuration(arg); err != nil {
+				return Faults{}, fmt.Errorf("httpclient: invalid fault latency %q", field)
+			}
+		case "error":
+			faults.ErrorRate = rate
+		case "timeout":
+			faults.TimeoutRate = rate
+			if hasArg {
+				if faults.TimeoutAfter, err = time.ParseDuration(arg); err != nil {
+					return Faults{}, fmt.Errorf("httpclient: invalid fault timeout %q", field)
+				}
+			}
+		case "status":
+			faults.StatusRate = rate
+			if hasArg {
+				for _, code := range strings.Split(arg, "|") {
+					status, err := strconv.Atoi(code)
+					if err != nil || status < 100 || status > 599 {
+						return Faults{}, fmt.Errorf("httpclient: invalid fault status %q", field)
+					}
+					faults.StatusCodes = append(faults.StatusCodes, status)
+				}
+			}
+		case "truncate":
+			faults.TruncateRate = rate
+		default:
+			return Faults{}, fmt.Errorf("httpclient: unknown fault %q", field)
+		}
+	}
+	return faults, nil
+}
@@ -0,0 +1,198 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newFaultServer(calls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		w.Write([]byte("0123456789"))
+	}))
+}
+
+func TestFaultInjectionMiddleware_Seeded(t *testing.T) {
+	var calls int32
+	server := newFaultServer(&calls)
+	defer server.Close()
+
+	outcomes := func() []bool {
+		client := NewClient(WithBaseURL(server.URL), WithMiddleware(FaultInjectionMiddleware(&FaultOptions{
+			Enabled: true,
+			Default: Faults{ErrorRate: 0.5},
+			Seed:    42,
+		})))
+		var failed []bool
+		for i := 0; i < 40; i++ {
+			_, err := client.Get("/test", nil)
+			if err != nil && !errors.Is(err, ErrInjectedFault) {
+				t.Fatalf("Expected an injected fault, got %v", err)
+			}
+			failed = append(failed, err != nil)
+		}
+		return failed
+	}
+
+	first, second := outcomes(), outcomes()
+	if !reflect.DeepEqual(first, second) {
+		t.Error("Expected the same seed to inject the same faults")
+	}
+	failures := 0
+	for _, failed := range first {
+		if failed {
+			failures++
+		}
+	}
+	if failures == 0 || failures == len(first) {
+		t.Errorf("Expected some of the requests to fail, got %d of %d", failures, len(first))
+	}
+	if int(calls) != 2*(len(first)-failures) {
+		t.Errorf("Expected failed requests not to reach the server, got %d calls", calls)
+	}
+}
+
+func TestFaultInjectionMiddleware_Routes(t *testing.T) {
+	var calls int32
+	server := newFaultServer(&calls)
+	defer server.Close()
+
+	client := NewClient(WithBaseURL(server.URL), WithMiddleware(FaultInjectionMiddleware(&FaultOptions{
+		Enabled: true,
+		Routes: map[string]Faults{
+			"/users/{id}": {StatusRate: 1, StatusCodes: []int{http.StatusBadGateway}},
+			"/download":   {TruncateRate: 1},
+			"/slow":       {LatencyRate: 1, Latency: 30 * time.Millisecond},
+		},
+	})))
+
+	resp, err := client.Get("/users/1", &RequestOptions{Route: "/users/{id}"})
+	if err != nil || resp.GetStatusCode() != http.StatusBadGateway {
+		t.Errorf("Expected injected 502, got %v", err)
+	}
+
+	resp, err = client.Get("/download", nil)
+	if err != nil || resp.GetBody() != "01234" {
+		t.Errorf("Expected truncated body, got '%s' (%v)", resp.GetBody(), err)
+	}
+
+	start := time.Now()
+	resp, err = client.Get("/slow", nil)
+	if err != nil || resp.GetBody() != "0123456789" {
+		t.Errorf("Expected the full response, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("Expected added latency, took %v", elapsed)
+	}
+
+	if calls != 2 {
+		t.Errorf("Expected 2 requests to reach the server, got %d", calls)
+	}
+}
+
+func TestFaultInjectionMiddleware_Timeout(t *testing.T) {
+	var calls int32
+	server := newFaultServer(&calls)
+	defer server.Close()
+
+	injector := FaultInjectionMiddleware(&FaultOptions{
+		Enabled: true,
+		Default: Faults{TimeoutRate: 1, TimeoutAfter: 10 * time.Millisecond},
+	})
+	client := NewClient(WithBaseURL(server.URL), WithMiddleware(injector))
+
+	_, err := client.Get("/test", nil)
+	var faultErr *FaultError
+	if !errors.As(err, &faultErr) || !faultErr.Timeout() || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected injected timeout, got %v", err)
+	}
+
+	client = NewClient(WithBaseURL(server.URL), WithMiddleware(TimeoutMiddleware(5*time.Millisecond), FaultInjectionMiddleware(&FaultOptions{
+		Enabled: true,
+		Default: Faults{TimeoutRate: 1},
+	})))
+	if _, err := client.Get("/test", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the request deadline to end the timeout, got %v", err)
+	}
+}
+
+func TestFaultInjectionMiddleware_Composes(t *testing.T) {
+	var calls int32
+	server := newFaultServer(&calls)
+	defer server.Close()
+
+	breaker := NewBreaker(&BreakerOptions{MinRequests: 6, FailureRate: 0.5})
+	var attempts int32
+	counter := func(next Handler) Handler {
+		return func(req *http.Request) (*Response, error) {
+			atomic.AddInt32(&attempts, 1)
+			return next(req)
+		}
+	}
+	client := NewClient(WithBaseURL(server.URL), WithMiddleware(
+		RetryMiddleware(2, &ExponentialBackoff{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}),
+		CircuitBreakerMiddleware(breaker),
+		counter,
+		FaultInjectionMiddleware(&FaultOptions{Enabled: true, Default: Faults{ErrorRate: 1}}),
+	))
+
+	if _, err := client.Get("/test", nil); !errors.Is(err, ErrInjectedFault) {
+		t.Errorf("Expected injected fault after retries, got %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+
+	client.Get("/test", nil)
+	if state := breaker.State(mustParseURL(t, server.URL).Host); state != StateOpen {
+		t.Errorf("Expected injected faults to open the circuit, got %s", state)
+	}
+	if calls != 0 {
+		t.Errorf("Expected no request to reach the server, got %d", calls)
+	}
+}
+
+func TestFaultInjectionMiddleware_Disabled(t *testing.T) {
+	var calls int32
+	server := newFaultServer(&calls)
+	defer server.Close()
+
+	client := NewClient(WithBaseURL(server.URL), WithMiddleware(FaultInjectionMiddleware(&FaultOptions{
+		Default: Faults{ErrorRate: 1},
+	})))
+	if _, err := client.Get("/test", nil); err != nil {
+		t.Errorf("Expected disabled injection to pass requests through, got %v", err)
+	}
+}
+
+func TestParseFaults(t *testing.T) {
+	faults, err := ParseFaults("latency=0.1:200ms, error=0.05,timeout=0.01:5s,status=0.2:502|503,truncate=0.02")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := Faults{
+		LatencyRate:  0.1,
+		Latency:      200 * time.Millisecond,
+		ErrorRate:    0.05,
+		TimeoutRate:  0.01,
+		TimeoutAfter: 5 * time.Second,
+		StatusRate:   0.2,
+		StatusCodes:  []int{502, 503},
+		TruncateRate: 0.02,
+	}
+	if !reflect.DeepEqual(faults, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, faults)
+	}
+
+	for _, spec := range []string{"error=2", "latency=0.1", "status=0.1:abc", "chaos=0.1"} {
+		if _, err := ParseFaults(spec); err == nil {
+			t.Errorf("Expected an error for %q", spec)
+		}
+	}
+}
@@ -1,8 +1,10 @@
-module github.com/augustoberwaldt/go-request-client
-
-go 1.21
-
-require (
-	github.com/stretchr/testify v1.8.4
-	golang.org/x/net v0.17.0
-) 
\ No newline at end of file
+module github.com/augustoberwaldt/go-request-client
+
+go 1.21
+
+require (
+	github.com/stretchr/testify v1.8.4
+	golang.org/x/net v0.17.0
+)
+
+require golang.org/x/text v0.13.0 // indirect
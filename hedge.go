@@ -0,0 +1,223 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HedgeOptions configures HedgeMiddleware
+type HedgeOptions struct {
+	// MaxHedges is the number of extra requests that may be fired,
+	// defaults to 1
+	MaxHedges int
+	// Percentile of observed latency after which a hedge is fired,
+	// defaults to 0.95
+	Percentile float64
+	// Delay is used until MinSamples latencies have been observed for a
+	// route, defaults to 100ms
+	Delay      time.Duration
+	MinSamples int // defaults to 20
+
+	// Routes limits hedging to these route templates; empty hedges all
+	Routes []string
+	// AllowNonIdempotent also hedges POST and PATCH requests
+	AllowNonIdempotent bool
+}
+
+// HedgeMiddleware fires an identical request when no response arrived
+// within the observed p95 latency of the route, and returns whichever
+// succeeds first. The other requests are cancelled. Failures do not fire
+// hedges: when every request fails, the error of the first one is returned.
+func HedgeMiddleware(options *HedgeOptions) Middleware {
+	h := &hedger{latencies: make(map[string]*latencyWindow)}
+	if options != nil {
+		h.options = *options
+	}
+	if h.options.MaxHedges <= 0 {
+		h.options.MaxHedges = 1
+	}
+	if h.options.Percentile <= 0 || h.options.Percentile >= 1 {
+		h.options.Percentile = 0.95
+	}
+	if h.options.Delay <= 0 {
+		h.options.Delay = 100 * time.Millisecond
+	}
+	if h.options.MinSamples <= 0 {
+		h.options.MinSamples = 20
+	}
+	if len(h.options.Routes) > 0 {
+		h.routes = make(map[string]bool)
+		for _, route := range h.options.Routes {
+			h.routes[route] = true
+		}
+	}
+
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*Response, error) {
+			if !h.enabled(req) {
+				return next(req)
+			}
+			return h.do(req, next)
+		}
+	}
+}
+
+// hedger holds the latency history of a HedgeMiddleware
+type hedger struct {
+	options   HedgeOptions
+	routes    map[string]bool
+	mu        sync.Mutex
+	latencies map[string]*latencyWindow
+}
+
+// hedgeAttempt is the result of one of the hedged requests
+type hedgeAttempt struct {
+	primary  bool
+	resp     *Response
+	err      error
+	duration time.Duration
+}
+
+// enabled reports whether req may be hedged
+func (h *hedger) enabled(req *http.Request) bool {
+	if isStreaming(req) || !canResend(req) {
+		return false
+	}
+	if !h.options.AllowNonIdempotent && !isIdempotentMethod(req.Method) {
+		return false
+	}
+	return h.routes == nil || h.routes[routeOf(req)]
+}
+
+// do sends req and its hedges
+func (h *hedger) do(req *http.Request, next Handler) (*Response, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	route := routeOf(req)
+	delay := h.delay(route)
+	settled := make(chan hedgeAttempt, h.options.MaxHedges+1)
+	launched, pending := 0, 0
+
+	launch := func() error {
+		attemptReq, err := cloneRequest(ctx, req)
+		if err != nil {
+			return err
+		}
+		primary := launched == 0
+		launched++
+		pending++
+
+		go func() {
+			start := time.Now()
+			resp, err := next(attemptReq)
+			settled <- hedgeAttempt{primary: primary, resp: resp, err: err, duration: time.Since(start)}
+		}()
+		return nil
+	}
+
+	start := time.Now()
+	if err := launch(); err != nil {
+		return nil, err
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	primaryDone := false
+	var primaryResp *Response
+	var primaryErr error
+	for {
+		select {
+		case attempt := <-settled:
+			pending--
+			if attempt.primary {
+				primaryDone = true
+				primaryResp, primaryErr = attempt.resp, attempt.err
+			}
+			if attempt.err == nil {
+				// Only successes are observed, as fast failures would
+				// lower the hedge delay
+				h.observe(route, attempt.duration)
+				if !primaryDone {
+					// The primary is cancelled, but it took at least this long
+					h.observe(route, time.Since(start))
+				}
+				return attempt.resp, nil
+			}
+			if pending == 0 {
+				return primaryResp, primaryErr
+			}
+
+		case <-timer.C:
+			if launched <= h.options.MaxHedges && !primaryDone && ctx.Err() == nil {
+				if err := launch(); err != nil {
+					return nil, err
+				}
+				timer.Reset(delay)
+			}
+		}
+	}
+}
+
+// delay returns the hedge delay for route
+func (h *hedger) delay(route string) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	window, ok := h.latencies[route]
+	if !ok || window.count < h.options.MinSamples {
+		return h.options.Delay
+	}
+	return window.percentile(h.options.Percentile)
+}
+
+// observe records the latency of a request
+func (h *hedger) observe(route string, latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	window, ok := h.latencies[route]
+	if !ok {
+		window = &latencyWindow{}
+		h.latencies[route] = window
+	}
+	window.add(latency)
+}
+
+// latencyWindow keeps the most recent latencies of a route
+type latencyWindow struct {
+	samples [128]time.Duration
+	next    int
+	count   int
+}
+
+// add records a latency, overwriting the oldest one when full
+func (w *latencyWindow) add(latency time.Duration) {
+	w.samples[w.next] = latency
+	w.next = (w.next + 1) % len(w.samples)
+	if w.count < len(w.samples) {
+		w.count++
+	}
+}
+
+// percentile returns the latency below which the fraction p of samples fall
+func (w *latencyWindow) percentile(p float64) time.Duration {
+	sorted := make([]time.Duration, w.count)
+	copy(sorted, w.samples[:w.count])
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[int(p*float64(len(sorted)-1))]
+}
+
+// isIdempotentMethod reports whether method is idempotent (RFC 9110
+// section 9.2.2)
+func isIdempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
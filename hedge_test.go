@@ -0,0 +1,187 @@
+package httpclient
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHedgeMiddleware(t *testing.T) {
+	var calls int32
+	var cancelled atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+				cancelled.Store(true)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("fast"))
+	}))
+	defer server.Close()
+
+	client := NewClient(
+		WithBaseURL(server.URL),
+		WithMiddleware(HedgeMiddleware(&HedgeOptions{Delay: 20 * time.Millisecond})),
+	)
+
+	start := time.Now()
+	resp, err := client.Get("/replicated", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected hedged request to answer quickly, took %v", elapsed)
+	}
+	if resp.GetBody() != "fast" {
+		t.Errorf("Expected body from the hedged request, got '%s'", resp.GetBody())
+	}
+	if atomic.LoadInt32(&calls) != 2 {
+		t.Errorf("Expected 2 upstream calls, got %d", calls)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for !cancelled.Load() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if !cancelled.Load() {
+		t.Error("Expected the slow request to be cancelled")
+	}
+}
+
+func TestHedgeMiddleware_NonIdempotent(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	client := NewClient(
+		WithBaseURL(server.URL),
+		WithMiddleware(HedgeMiddleware(&HedgeOptions{Delay: 5 * time.Millisecond, MaxHedges: 3})),
+	)
+
+	if _, err := client.Post("/orders", &RequestOptions{JSON: map[string]int{"qty": 1}}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("Expected POST not to be hedged, got %d calls", calls)
+	}
+}
+
+func TestHedgeMiddleware_Routes(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(
+		WithBaseURL(server.URL),
+		WithMiddleware(HedgeMiddleware(&HedgeOptions{Delay: 5 * time.Millisecond, Routes: []string{"/search"}})),
+	)
+
+	client.Get("/users", nil)
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("Expected routes without hedging to be sent once, got %d calls", calls)
+	}
+}
+
+func TestHedgeMiddleware_PrimaryError(t *testing.T) {
+	var calls int32
+	failure := errors.New("connection reset")
+	handler := HedgeMiddleware(&HedgeOptions{Delay: 20 * time.Millisecond})(func(req *http.Request) (*Response, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, failure
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com/replicated", nil)
+	if _, err := handler(req); err != failure {
+		t.Errorf("Expected the primary error unchanged, got %v", err)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("Expected a failure not to fire a hedge, got %d calls", calls)
+	}
+}
+
+func TestHedgeMiddleware_ObservesPrimaryLatency(t *testing.T) {
+	h := &hedger{
+		options:   HedgeOptions{MaxHedges: 1, Delay: 10 * time.Millisecond},
+		latencies: make(map[string]*latencyWindow),
+	}
+	var calls int32
+	next := func(req *http.Request) (*Response, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		}
+		return &Response{}, nil
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com/replicated", nil)
+	if _, err := h.do(req, next); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	window := h.latencies["/replicated"]
+	if window == nil || window.count != 2 {
+		t.Fatalf("Expected the latencies of both requests to be observed, got %v", window)
+	}
+	if slowest := window.percentile(1); slowest < 10*time.Millisecond {
+		t.Errorf("Expected the primary to be observed for at least the hedge delay, got %v", slowest)
+	}
+}
+
+func TestHedgeMiddleware_IgnoresFailureLatency(t *testing.T) {
+	h := &hedger{
+		options:   HedgeOptions{MaxHedges: 1, Delay: 10 * time.Millisecond},
+		latencies: make(map[string]*latencyWindow),
+	}
+	next := func(req *http.Request) (*Response, error) {
+		return nil, errors.New("connection refused")
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com/replicated", nil)
+	h.do(req, next)
+
+	if window := h.latencies["/replicated"]; window != nil {
+		t.Errorf("Expected failed requests not to be observed, got %d samples", window.count)
+	}
+}
+
+func TestHedgeMiddleware_NonReplayableBody(t *testing.T) {
+	var calls int32
+	handler := HedgeMiddleware(&HedgeOptions{Delay: time.Millisecond, AllowNonIdempotent: true})(func(req *http.Request) (*Response, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(20 * time.Millisecond)
+		body, _ := io.ReadAll(req.Body)
+		if string(body) != "payload" {
+			t.Errorf("Expected the body unchanged, got '%s'", body)
+		}
+		return &Response{}, nil
+	})
+
+	// A MultiReader hides the body type, so http.NewRequest sets no GetBody
+	req, _ := http.NewRequest(http.MethodPost, "http://example.com/orders", io.MultiReader(strings.NewReader("payload")))
+	if _, err := handler(req); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("Expected a non-replayable body not to be hedged, got %d calls", calls)
+	}
+}
@@ -0,0 +1,149 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// HTTP2Mode controls how the client negotiates HTTP/2
+type HTTP2Mode int
+
+const (
+	// HTTP2Auto negotiates HTTP/2 over TLS via ALPN and falls back to HTTP/1.1
+	HTTP2Auto HTTP2Mode = iota
+	// HTTP2Force only speaks HTTP/2 over TLS and fails if the server does not
+	HTTP2Force
+	// HTTP2PriorKnowledge speaks cleartext HTTP/2 (h2c) without an upgrade
+	HTTP2PriorKnowledge
+	// HTTP2Disabled always uses HTTP/1.1
+	HTTP2Disabled
+)
+
+// String returns the name of the mode
+func (m HTTP2Mode) String() string {
+	switch m {
+	case HTTP2Auto:
+		return "auto"
+	case HTTP2Force:
+		return "force-h2"
+	case HTTP2PriorKnowledge:
+		return "h2c-prior-knowledge"
+	case HTTP2Disabled:
+		return "disabled"
+	default:
+		return "unknown"
+	}
+}
+
+// http2Config holds the HTTP/2 settings collected from client options
+type http2Config struct {
+	mode            HTTP2Mode
+	readIdleTimeout time.Duration
+	pingTimeout     time.Duration
+}
+
+// WithHTTP2 sets the HTTP/2 negotiation mode. The settings apply to the
+// default transport or to an *http.Transport set with WithTransport; with
+// an unknown mode or another transport type, every request of the client
+// fails.
+func WithHTTP2(mode HTTP2Mode) ClientOption {
+	return func(c *Client) {
+		if mode < HTTP2Auto || mode > HTTP2Disabled {
+			c.setErr(fmt.Errorf("httpclient: unknown HTTP/2 mode %d", mode))
+			return
+		}
+		c.http2.mode = mode
+	}
+}
+
+// WithHTTP2HealthCheck enables ping-based health checks on idle HTTP/2
+// connections. A ping is sent after readIdleTimeout without any frames and
+// the connection is closed if no reply arrives within pingTimeout. Every
+// request of the client fails on negative timeouts.
+func WithHTTP2HealthCheck(readIdleTimeout, pingTimeout time.Duration) ClientOption {
+	return func(c *Client) {
+		if readIdleTimeout < 0 || pingTimeout < 0 {
+			c.setErr(fmt.Errorf("httpclient: negative HTTP/2 health check timeouts %v/%v", readIdleTimeout, pingTimeout))
+			return
+		}
+		c.http2.readIdleTimeout = readIdleTimeout
+		c.http2.pingTimeout = pingTimeout
+	}
+}
+
+// newTransport builds the round tripper for the configured HTTP/2 mode
+// from base, a copy of which is used when it is an *http.Transport. Base
+// defaults to http.DefaultTransport.
+func (cfg http2Config) newTransport(base http.RoundTripper) (http.RoundTripper, error) {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	baseTransport, ok := base.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("settings cannot be applied to a %T transport", base)
+	}
+	t := baseTransport.Clone()
+
+	switch cfg.mode {
+	case HTTP2Force:
+		return cfg.configure(&http2.Transport{TLSClientConfig: t.TLSClientConfig}), nil
+
+	case HTTP2PriorKnowledge:
+		dial := t.DialContext
+		if dial == nil {
+			dial = (&net.Dialer{}).DialContext
+		}
+		h2 := &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dial(ctx, network, addr)
+			},
+		}
+		return cfg.configure(h2), nil
+
+	case HTTP2Disabled:
+		t.ForceAttemptHTTP2 = false
+		if t.TLSClientConfig == nil {
+			t.TLSClientConfig = &tls.Config{}
+		}
+		t.TLSClientConfig.NextProtos = []string{"http/1.1"}
+		t.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+		return t, nil
+
+	default:
+		h2, err := http2.ConfigureTransports(t)
+		if err != nil {
+			return nil, err
+		}
+		cfg.configure(h2)
+		return t, nil
+	}
+}
+
+// configure applies the health check settings to an HTTP/2 transport
+func (cfg http2Config) configure(t *http2.Transport) *http2.Transport {
+	t.ReadIdleTimeout = cfg.readIdleTimeout
+	t.PingTimeout = cfg.pingTimeout
+	return t
+}
+
+// GetProtocol returns the negotiated protocol of the response as an ALPN
+// identifier: "h2", "h2c", "http/1.1" or "http/1.0"
+func (r *Response) GetProtocol() string {
+	switch {
+	case r.ProtoMajor == 2 && r.TLS != nil:
+		return "h2"
+	case r.ProtoMajor == 2:
+		return "h2c"
+	case r.ProtoMajor == 1 && r.ProtoMinor == 0:
+		return "http/1.0"
+	default:
+		return "http/1.1"
+	}
+}
@@ -0,0 +1,117 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+func TestClient_HTTP2PriorKnowledge(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 {
+			t.Errorf("Expected HTTP/2 request, got %s", r.Proto)
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"message": "success"}`))
+	})
+	server := httptest.NewServer(h2c.NewHandler(handler, &http2.Server{}))
+	defer server.Close()
+
+	client := NewClient(WithBaseURL(server.URL), WithHTTP2(HTTP2PriorKnowledge))
+	resp, err := client.Get("/test", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if resp.GetProtocol() != "h2c" {
+		t.Errorf("Expected protocol to be 'h2c', got '%s'", resp.GetProtocol())
+	}
+}
+
+func TestClient_HTTP2Disabled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithBaseURL(server.URL), WithHTTP2(HTTP2Disabled))
+	resp, err := client.Get("/test", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if resp.GetProtocol() != "http/1.1" {
+		t.Errorf("Expected protocol to be 'http/1.1', got '%s'", resp.GetProtocol())
+	}
+}
+
+func TestClient_HTTP2HealthCheck(t *testing.T) {
+	client := NewClient(WithHTTP2(HTTP2Force), WithHTTP2HealthCheck(30*time.Second, 15*time.Second))
+
+	transport, ok := client.httpClient.Transport.(*http2.Transport)
+	if !ok {
+		t.Fatalf("Expected *http2.Transport, got %T", client.httpClient.Transport)
+	}
+
+	if transport.ReadIdleTimeout != 30*time.Second || transport.PingTimeout != 15*time.Second {
+		t.Errorf("Expected health check timeouts 30s/15s, got %v/%v", transport.ReadIdleTimeout, transport.PingTimeout)
+	}
+}
+
+func TestWithHTTP2_Invalid(t *testing.T) {
+	for name, option := range map[string]ClientOption{
+		"mode":         WithHTTP2(HTTP2Mode(42)),
+		"health check": WithHTTP2HealthCheck(-time.Second, time.Second),
+	} {
+		client := NewClient(WithBaseURL("http://127.0.0.1:1"), option)
+		if _, err := client.Get("/test", nil); err == nil {
+			t.Errorf("Expected an invalid %s to fail the request", name)
+		}
+	}
+}
+
+func TestWithHTTP2_CustomTransport(t *testing.T) {
+	base := &http.Transport{MaxIdleConns: 7}
+	client := NewClient(WithTransport(base), WithHTTP2(HTTP2Disabled))
+
+	transport, ok := client.httpClient.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("Expected *http.Transport, got %T", client.httpClient.Transport)
+	}
+	if transport == base {
+		t.Error("Expected the transport passed in to be left unchanged")
+	}
+	if transport.MaxIdleConns != 7 {
+		t.Errorf("Expected MaxIdleConns 7 to be kept, got %d", transport.MaxIdleConns)
+	}
+	if transport.TLSNextProto == nil || transport.ForceAttemptHTTP2 {
+		t.Error("Expected HTTP/2 to be disabled on the transport passed in")
+	}
+}
+
+func TestWithHTTP2_UnsupportedTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected no request to be sent")
+	}))
+	defer server.Close()
+
+	client := NewClient(
+		WithBaseURL(server.URL),
+		WithTransport(roundTripperFunc(http.DefaultTransport.RoundTrip)),
+		WithHTTP2(HTTP2Force),
+	)
+	if _, err := client.Get("/test", nil); err == nil {
+		t.Error("Expected HTTP/2 settings on a custom round tripper to fail the request")
+	}
+}
+
+// roundTripperFunc adapts a function to http.RoundTripper
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
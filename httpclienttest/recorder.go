@@ -0,0 +1,482 @@
+package httpclienttest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	httpclient "github.com/augustoberwaldt/go-request-client"
+	"github.com/augustoberwaldt/go-request-client/internal/redact"
+)
+
+// ErrInteractionNotFound is returned when a replayed request matches no
+// recorded interaction
+var ErrInteractionNotFound = errors.New("httpclienttest: no recorded interaction")
+
+// Redacted replaces secret values in cassettes
+const Redacted = redact.Redacted
+
+// RecorderMode selects how a Recorder uses its cassette
+type RecorderMode int
+
+const (
+	// ModeReplay serves requests from the cassette without the network.
+	// Unmatched requests fail with ErrInteractionNotFound.
+	ModeReplay RecorderMode = iota
+	// ModeRecord sends every request and replaces the cassette
+	ModeRecord
+	// ModeNewEpisodes serves matched requests from the cassette and sends
+	// and records the others
+	ModeNewEpisodes
+	// ModeStrict replays each interaction at most once. Unmatched requests
+	// fail and AssertExpectations reports interactions never replayed.
+	ModeStrict
+)
+
+// String returns the name of the mode
+func (m RecorderMode) String() string {
+	switch m {
+	case ModeReplay:
+		return "replay"
+	case ModeRecord:
+		return "record"
+	case ModeNewEpisodes:
+		return "new-episodes"
+	case ModeStrict:
+		return "strict"
+	default:
+		return "unknown"
+	}
+}
+
+// Interaction is a recorded request and its response, one per cassette line
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the recorded form of a request
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+	// BodyEncoding is "base64" for bodies that are not valid UTF-8
+	BodyEncoding string `json:"body_encoding,omitempty"`
+}
+
+// RecordedResponse is the recorded form of a response
+type RecordedResponse struct {
+	StatusCode   int         `json:"status_code"`
+	Header       http.Header `json:"header,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+}
+
+// Matcher reports whether a live request, in its redacted recorded form,
+// matches a recorded one
+type Matcher func(live, recorded RecordedRequest) bool
+
+// MatchMethod matches requests with the same method
+func MatchMethod(live, recorded RecordedRequest) bool {
+	return live.Method == recorded.Method
+}
+
+// MatchURL matches requests with the same URL
+func MatchURL(live, recorded RecordedRequest) bool {
+	return live.URL == recorded.URL
+}
+
+// MatchPath matches requests with the same path, ignoring host and query
+func MatchPath(live, recorded RecordedRequest) bool {
+	liveURL, err := url.Parse(live.URL)
+	if err != nil {
+		return false
+	}
+	recordedURL, err := url.Parse(recorded.URL)
+	if err != nil {
+		return false
+	}
+	return liveURL.Path == recordedURL.Path
+}
+
+// MatchBody matches requests with the same body. JSON bodies are compared
+// by value, ignoring formatting and key order.
+func MatchBody(live, recorded RecordedRequest) bool {
+	if live.Body == recorded.Body && live.BodyEncoding == recorded.BodyEncoding {
+		return true
+	}
+	var liveJSON, recordedJSON interface{}
+	if json.Unmarshal([]byte(live.Body), &liveJSON) != nil || json.Unmarshal([]byte(recorded.Body), &recordedJSON) != nil {
+		return false
+	}
+	liveData, _ := json.Marshal(liveJSON)
+	recordedData, _ := json.Marshal(recordedJSON)
+	return bytes.Equal(liveData, recordedData)
+}
+
+// MatchHeaders returns a matcher comparing the named headers
+func MatchHeaders(names ...string) Matcher {
+	return func(live, recorded RecordedRequest) bool {
+		for _, name := range names {
+			if strings.Join(live.Header.Values(name), ", ") != strings.Join(recorded.Header.Values(name), ", ") {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// RecorderOptions configures a Recorder
+type RecorderOptions struct {
+	Mode RecorderMode
+	// Matchers must all match for a recorded interaction to be replayed,
+	// defaults to MatchMethod and MatchURL
+	Matchers []Matcher
+	// Transport sends requests that are recorded, defaults to
+	// http.DefaultTransport
+	Transport http.RoundTripper
+
+	RedactHeaders     []string // in addition to Authorization and cookies
+	RedactQueryParams []string
+	RedactJSONFields  []string // at any depth of JSON request and response bodies
+	// Redact is called on every interaction before it is written, and on
+	// live requests, with an empty response, before they are matched
+	Redact func(*Interaction)
+}
+
+// Recorder is an http.RoundTripper recording interactions to a cassette
+// file and replaying them
+type Recorder struct {
+	path    string
+	options RecorderOptions
+
+	headers     redact.Set
+	queryParams redact.Set
+	jsonFields  redact.Set
+
+	mu           sync.Mutex
+	interactions []*Interaction
+	played       map[*Interaction]bool
+	dirty        bool
+	unmatched    []string
+}
+
+// NewRecorder creates a recorder for the cassette at path. The cassette
+// must exist unless the mode records.
+func NewRecorder(path string, options *RecorderOptions) (*Recorder, error) {
+	r := &Recorder{path: path, played: make(map[*Interaction]bool)}
+	if options != nil {
+		r.options = *options
+	}
+	if len(r.options.Matchers) == 0 {
+		r.options.Matchers = []Matcher{MatchMethod, MatchURL}
+	}
+	if r.options.Transport == nil {
+		r.options.Transport = http.DefaultTransport
+	}
+	r.headers = redact.NewSet(redact.DefaultHeaders, r.options.RedactHeaders)
+	r.queryParams = redact.NewSet(r.options.RedactQueryParams)
+	r.jsonFields = redact.NewSet(r.options.RedactJSONFields)
+
+	if r.options.Mode == ModeRecord {
+		r.dirty = true
+		return r, nil
+	}
+
+	interactions, err := readCassette(path)
+	if errors.Is(err, os.ErrNotExist) && r.options.Mode == ModeNewEpisodes {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.interactions = interactions
+	return r, nil
+}
+
+// Option plugs the recorder into httpclient.NewClient
+func (r *Recorder) Option() httpclient.ClientOption {
+	return httpclient.WithTransport(r)
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	live := r.recordRequest(req, body)
+
+	if r.options.Mode != ModeRecord {
+		matched := r.redactRequest(live)
+		if interaction := r.find(matched); interaction != nil {
+			return interaction.Response.response(req)
+		}
+		if r.options.Mode != ModeNewEpisodes {
+			r.mu.Lock()
+			r.unmatched = append(r.unmatched, matched.Method+" "+matched.URL)
+			r.mu.Unlock()
+			return nil, fmt.Errorf("%w for %s %s", ErrInteractionNotFound, matched.Method, matched.URL)
+		}
+	}
+
+	sent := req.Clone(req.Context())
+	sent.Body = io.NopCloser(bytes.NewReader(body))
+	resp, err := r.options.Transport.RoundTrip(sent)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	interaction := &Interaction{Request: live, Response: r.recordResponse(resp, respBody)}
+	if r.options.Redact != nil {
+		r.options.Redact(interaction)
+	}
+	r.mu.Lock()
+	r.interactions = append(r.interactions, interaction)
+	r.played[interaction] = true
+	r.dirty = true
+	r.mu.Unlock()
+
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	return resp, nil
+}
+
+// Stop writes the cassette when interactions were recorded
+func (r *Recorder) Stop() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.dirty {
+		return nil
+	}
+	if err := writeCassette(r.path, r.interactions); err != nil {
+		return err
+	}
+	r.dirty = false
+	return nil
+}
+
+// AssertExpectations reports requests that matched no interaction and, in
+// strict mode, interactions that were never replayed
+func (r *Recorder) AssertExpectations(t TestingT) bool {
+	t.Helper()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ok := true
+	for _, description := range r.unmatched {
+		t.Errorf("httpclienttest: no recorded interaction for %s", description)
+		ok = false
+	}
+	if r.options.Mode == ModeStrict {
+		for _, interaction := range r.interactions {
+			if !r.played[interaction] {
+				t.Errorf("httpclienttest: recorded interaction %s %s was not replayed", interaction.Request.Method, interaction.Request.URL)
+				ok = false
+			}
+		}
+	}
+	return ok
+}
+
+// find returns the first recorded interaction matching live
+func (r *Recorder) find(live RecordedRequest) *Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, interaction := range r.interactions {
+		if r.options.Mode == ModeStrict && r.played[interaction] {
+			continue
+		}
+		if r.matches(live, interaction.Request) {
+			r.played[interaction] = true
+			return interaction
+		}
+	}
+	return nil
+}
+
+// matches reports whether every matcher accepts the pair
+func (r *Recorder) matches(live, recorded RecordedRequest) bool {
+	for _, match := range r.options.Matchers {
+		if !match(live, recorded) {
+			return false
+		}
+	}
+	return true
+}
+
+// recordRequest returns the redacted recorded form of req
+func (r *Recorder) recordRequest(req *http.Request, body []byte) RecordedRequest {
+	u := *req.URL
+	if u.RawQuery != "" && len(r.queryParams) > 0 {
+		q := u.Query()
+		for name := range q {
+			if r.queryParams.Has(name) {
+				q.Set(name, Redacted)
+			}
+		}
+		u.RawQuery = q.Encode()
+	}
+
+	recorded := RecordedRequest{
+		Method: req.Method,
+		URL:    u.String(),
+		Header: r.redactHeader(req.Header),
+	}
+	recorded.Body, recorded.BodyEncoding = r.encodeBody(body, req.Header)
+	return recorded
+}
+
+// redactRequest applies the Redact hook to a copy of live, so that it
+// compares with recorded requests that went through the same hook
+func (r *Recorder) redactRequest(live RecordedRequest) RecordedRequest {
+	if r.options.Redact == nil {
+		return live
+	}
+	interaction := &Interaction{Request: live}
+	interaction.Request.Header = live.Header.Clone()
+	r.options.Redact(interaction)
+	return interaction.Request
+}
+
+// recordResponse returns the redacted recorded form of resp
+func (r *Recorder) recordResponse(resp *http.Response, body []byte) RecordedResponse {
+	recorded := RecordedResponse{
+		StatusCode: resp.StatusCode,
+		Header:     r.redactHeader(resp.Header),
+	}
+	recorded.Body, recorded.BodyEncoding = r.encodeBody(body, resp.Header)
+	return recorded
+}
+
+// redactHeader returns a copy of header with secret values redacted
+func (r *Recorder) redactHeader(header http.Header) http.Header {
+	if len(header) == 0 {
+		return nil
+	}
+	redactedHeader := header.Clone()
+	for name, values := range redactedHeader {
+		if r.headers.Has(name) {
+			for i := range values {
+				values[i] = Redacted
+			}
+		}
+	}
+	return redactedHeader
+}
+
+// encodeBody redacts JSON fields from body and encodes it for the cassette
+func (r *Recorder) encodeBody(body []byte, header http.Header) (string, string) {
+	body = r.jsonFields.JSONBody(body, header)
+	if !utf8.Valid(body) {
+		return base64.StdEncoding.EncodeToString(body), "base64"
+	}
+	return string(body), ""
+}
+
+// response rebuilds the recorded response for req
+func (rr RecordedResponse) response(req *http.Request) (*http.Response, error) {
+	body := []byte(rr.Body)
+	if rr.BodyEncoding == "base64" {
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(rr.Body); err != nil {
+			return nil, err
+		}
+	}
+	header := rr.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rr.StatusCode, http.StatusText(rr.StatusCode)),
+		StatusCode:    rr.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// readCassette loads the interactions of a cassette file
+func readCassette(path string) ([]*Interaction, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var interactions []*Interaction
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		interaction := &Interaction{}
+		if err := json.Unmarshal(scanner.Bytes(), interaction); err != nil {
+			return nil, fmt.Errorf("httpclienttest: %s:%d: %w", path, line, err)
+		}
+		interactions = append(interactions, interaction)
+	}
+	return interactions, scanner.Err()
+}
+
+// writeCassette replaces the cassette file with interactions, one JSON
+// object per line
+func writeCassette(path string, interactions []*Interaction) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".cassette-*")
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, interaction := range interactions {
+		if err = encoder.Encode(interaction); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
@@ -0,0 +1,212 @@
+package httpclienttest
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	httpclient "github.com/augustoberwaldt/go-request-client"
+)
+
+func newEchoServer(calls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"path":"` + r.URL.Path + `","token":"secret-token"}`))
+	}))
+}
+
+func TestRecorder_RecordAndReplay(t *testing.T) {
+	var calls int32
+	server := newEchoServer(&calls)
+	cassette := filepath.Join(t.TempDir(), "users.jsonl")
+
+	recorder, err := NewRecorder(cassette, &RecorderOptions{
+		Mode:              ModeRecord,
+		RedactQueryParams: []string{"api_key"},
+		RedactJSONFields:  []string{"token"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+	client := httpclient.NewClient(
+		httpclient.WithBaseURL(server.URL),
+		httpclient.WithAuth("user", "password"),
+		recorder.Option(),
+	)
+	for _, path := range []string{"/users", "/users?api_key=abc"} {
+		if _, err := client.Get(path, nil); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if err := recorder.Stop(); err != nil {
+		t.Fatalf("Failed to write cassette: %v", err)
+	}
+	server.Close()
+
+	data, err := os.ReadFile(cassette)
+	if err != nil {
+		t.Fatalf("Failed to read cassette: %v", err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 2 {
+		t.Errorf("Expected 2 interactions, got %d", lines)
+	}
+	for _, secret := range []string{"secret-token", "api_key=abc", "Basic "} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("Expected %q to be redacted from the cassette", secret)
+		}
+	}
+
+	replayer, err := NewRecorder(cassette, &RecorderOptions{RedactQueryParams: []string{"api_key"}})
+	if err != nil {
+		t.Fatalf("Failed to create replayer: %v", err)
+	}
+	client = httpclient.NewClient(httpclient.WithBaseURL(server.URL), replayer.Option())
+
+	resp, err := client.Get("/users?api_key=other", nil)
+	if err != nil {
+		t.Fatalf("Expected replayed response, got %v", err)
+	}
+	if resp.GetBody() != `{"path":"/users","token":"[REDACTED]"}` {
+		t.Errorf("Unexpected replayed body: %s", resp.GetBody())
+	}
+	if resp.GetHeader("Content-Type") != "application/json" {
+		t.Errorf("Expected replayed Content-Type, got '%s'", resp.GetHeader("Content-Type"))
+	}
+
+	if _, err := client.Get("/orders", nil); !errors.Is(err, ErrInteractionNotFound) {
+		t.Errorf("Expected ErrInteractionNotFound, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 requests to reach the server, got %d", calls)
+	}
+}
+
+func TestRecorder_RedactHook(t *testing.T) {
+	var calls int32
+	server := newEchoServer(&calls)
+	defer server.Close()
+	cassette := filepath.Join(t.TempDir(), "signed.jsonl")
+
+	// The hook drops a signature that changes on every run
+	options := &RecorderOptions{
+		Redact: func(interaction *Interaction) {
+			u, _ := url.Parse(interaction.Request.URL)
+			q := u.Query()
+			q.Del("sig")
+			u.RawQuery = q.Encode()
+			interaction.Request.URL = u.String()
+		},
+	}
+
+	options.Mode = ModeRecord
+	recorder, err := NewRecorder(cassette, options)
+	if err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+	client := httpclient.NewClient(httpclient.WithBaseURL(server.URL), recorder.Option())
+	if _, err := client.Get("/users?sig=first", nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := recorder.Stop(); err != nil {
+		t.Fatalf("Failed to write cassette: %v", err)
+	}
+
+	options.Mode = ModeReplay
+	replayer, err := NewRecorder(cassette, options)
+	if err != nil {
+		t.Fatalf("Failed to create replayer: %v", err)
+	}
+	client = httpclient.NewClient(httpclient.WithBaseURL(server.URL), replayer.Option())
+	if _, err := client.Get("/users?sig=second", nil); err != nil {
+		t.Errorf("Expected the redacted live request to match, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 request to reach the server, got %d", calls)
+	}
+}
+
+func TestRecorder_NewEpisodes(t *testing.T) {
+	var calls int32
+	server := newEchoServer(&calls)
+	defer server.Close()
+	cassette := filepath.Join(t.TempDir(), "episodes.jsonl")
+
+	for i := 0; i < 2; i++ {
+		recorder, err := NewRecorder(cassette, &RecorderOptions{
+			Mode:     ModeNewEpisodes,
+			Matchers: []Matcher{MatchMethod, MatchPath},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create recorder: %v", err)
+		}
+		client := httpclient.NewClient(httpclient.WithBaseURL(server.URL), recorder.Option())
+		client.Get("/users", nil)
+		if i == 1 {
+			client.Get("/orders", nil)
+		}
+		if err := recorder.Stop(); err != nil {
+			t.Fatalf("Failed to write cassette: %v", err)
+		}
+	}
+
+	if calls != 2 {
+		t.Errorf("Expected only new episodes to reach the server, got %d requests", calls)
+	}
+	interactions, err := readCassette(cassette)
+	if err != nil || len(interactions) != 2 {
+		t.Errorf("Expected 2 recorded interactions, got %d (%v)", len(interactions), err)
+	}
+}
+
+func TestRecorder_Strict(t *testing.T) {
+	cassette := filepath.Join(t.TempDir(), "strict.jsonl")
+	interactions := []*Interaction{
+		{Request: RecordedRequest{Method: "POST", URL: "http://example.com/users", Body: `{"name":"Ada"}`}, Response: RecordedResponse{StatusCode: 201}},
+		{Request: RecordedRequest{Method: "GET", URL: "http://example.com/users"}, Response: RecordedResponse{StatusCode: 200, Body: "[]"}},
+	}
+	if err := writeCassette(cassette, interactions); err != nil {
+		t.Fatalf("Failed to write cassette: %v", err)
+	}
+
+	recorder, err := NewRecorder(cassette, &RecorderOptions{
+		Mode:     ModeStrict,
+		Matchers: []Matcher{MatchMethod, MatchURL, MatchBody},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create recorder: %v", err)
+	}
+	client := httpclient.NewClient(httpclient.WithBaseURL("http://example.com"), recorder.Option())
+
+	resp, err := client.Post("/users", &httpclient.RequestOptions{JSON: map[string]string{"name": "Ada"}})
+	if err != nil || resp.GetStatusCode() != 201 {
+		t.Fatalf("Expected replayed 201, got %v", err)
+	}
+	if _, err := client.Post("/users", &httpclient.RequestOptions{JSON: map[string]string{"name": "Ada"}}); !errors.Is(err, ErrInteractionNotFound) {
+		t.Errorf("Expected a strict cassette to replay interactions once, got %v", err)
+	}
+
+	failures := &recordingT{}
+	if recorder.AssertExpectations(failures) {
+		t.Error("Expected assertions to fail")
+	}
+	if len(failures.errors) != 2 {
+		t.Fatalf("Expected 2 failures, got %v", failures.errors)
+	}
+	if failures.errors[1] != "httpclienttest: recorded interaction GET http://example.com/users was not replayed" {
+		t.Errorf("Unexpected failure: %s", failures.errors[1])
+	}
+}
+
+func TestNewRecorder_MissingCassette(t *testing.T) {
+	_, err := NewRecorder(filepath.Join(t.TempDir(), "missing.jsonl"), nil)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected a missing cassette to fail in replay mode, got %v", err)
+	}
+}
@@ -0,0 +1,348 @@
+// Package httpclienttest provides a mock transport for testing code that
+// uses httpclient.Client without starting a server.
+package httpclienttest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	httpclient "github.com/augustoberwaldt/go-request-client"
+)
+
+// ErrUnexpectedRequest is returned for requests matching no expectation
+var ErrUnexpectedRequest = errors.New("httpclienttest: unexpected request")
+
+// TestingT is the subset of testing.TB used to report failed assertions
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// MockTransport is an http.RoundTripper answering requests from registered
+// expectations
+type MockTransport struct {
+	mu           sync.Mutex
+	expectations []*Expectation
+	requests     []*http.Request
+	unexpected   []string
+}
+
+// NewMockTransport creates a mock transport without expectations
+func NewMockTransport() *MockTransport {
+	return &MockTransport{}
+}
+
+// Option plugs the transport into httpclient.NewClient
+func (m *MockTransport) Option() httpclient.ClientOption {
+	return httpclient.WithTransport(m)
+}
+
+// Expect registers an expected request. The pattern is matched against the
+// full URL, or against the path and query when it starts with "/"; a "*"
+// matches any run of characters. An empty method matches every method.
+func (m *MockTransport) Expect(method, pattern string) *Expectation {
+	e := &Expectation{
+		method:  strings.ToUpper(method),
+		pattern: pattern,
+		url:     globPattern(pattern),
+		times:   1,
+		status:  http.StatusOK,
+		header:  make(http.Header),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expectations = append(m.expectations, e)
+	return e
+}
+
+// RoundTrip implements http.RoundTripper
+func (m *MockTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	m.mu.Lock()
+	m.requests = append(m.requests, req)
+	var match *Expectation
+	for _, e := range m.expectations {
+		if e.remaining() && e.matches(req, body) {
+			match = e
+			break
+		}
+	}
+	if match == nil {
+		description := describe(req)
+		m.unexpected = append(m.unexpected, description)
+		m.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrUnexpectedRequest, description)
+	}
+	match.calls++
+	m.mu.Unlock()
+
+	if match.delay > 0 {
+		timer := time.NewTimer(match.delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+	return match.response(req)
+}
+
+// Requests returns every request received by the transport
+func (m *MockTransport) Requests() []*http.Request {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*http.Request(nil), m.requests...)
+}
+
+// AssertExpectations reports expectations that were not met and requests
+// that matched no expectation, and returns whether there were none
+func (m *MockTransport) AssertExpectations(t TestingT) bool {
+	t.Helper()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ok := true
+	for _, e := range m.expectations {
+		if e.times > 0 && e.calls < e.times {
+			t.Errorf("httpclienttest: expected %s to be called %d times, got %d", e, e.times, e.calls)
+			ok = false
+		}
+	}
+	for _, description := range m.unexpected {
+		t.Errorf("httpclienttest: unexpected request %s", description)
+		ok = false
+	}
+	return ok
+}
+
+// Expectation is an expected request and its canned response
+type Expectation struct {
+	method  string
+	pattern string
+	url     *regexp.Regexp
+	headers http.Header
+	body    []func([]byte) bool
+	times   int // 0 means any number of times
+	calls   int
+
+	status   int
+	header   http.Header
+	respBody []byte
+	err      error
+	delay    time.Duration
+}
+
+// WithHeader requires the request to carry the header value
+func (e *Expectation) WithHeader(name, value string) *Expectation {
+	if e.headers == nil {
+		e.headers = make(http.Header)
+	}
+	e.headers.Add(name, value)
+	return e
+}
+
+// WithJSONBody requires the request body to be JSON equal to v
+func (e *Expectation) WithJSONBody(v interface{}) *Expectation {
+	expected, err := normalizeJSON(v)
+	return e.WithBodyMatcher(func(body []byte) bool {
+		var actual interface{}
+		if err != nil || json.Unmarshal(body, &actual) != nil {
+			return false
+		}
+		return reflect.DeepEqual(expected, actual)
+	})
+}
+
+// WithJSONField requires the request body to be a JSON object whose field
+// at path, separated by dots, equals v
+func (e *Expectation) WithJSONField(path string, v interface{}) *Expectation {
+	expected, err := normalizeJSON(v)
+	return e.WithBodyMatcher(func(body []byte) bool {
+		var actual interface{}
+		if err != nil || json.Unmarshal(body, &actual) != nil {
+			return false
+		}
+		for _, key := range strings.Split(path, ".") {
+			object, ok := actual.(map[string]interface{})
+			if !ok {
+				return false
+			}
+			if actual, ok = object[key]; !ok {
+				return false
+			}
+		}
+		return reflect.DeepEqual(expected, actual)
+	})
+}
+
+// WithBodyMatcher requires the request body to satisfy match
+func (e *Expectation) WithBodyMatcher(match func(body []byte) bool) *Expectation {
+	e.body = append(e.body, match)
+	return e
+}
+
+// Times sets how many requests the expectation answers, defaults to 1
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+// AnyTimes lets the expectation answer any number of requests, including none
+func (e *Expectation) AnyTimes() *Expectation {
+	e.times = 0
+	return e
+}
+
+// Respond sets the status and body of the response
+func (e *Expectation) Respond(status int, body string) *Expectation {
+	e.status = status
+	e.respBody = []byte(body)
+	return e
+}
+
+// RespondJSON sets the status of the response and its body to v encoded
+// as JSON
+func (e *Expectation) RespondJSON(status int, v interface{}) *Expectation {
+	body, err := json.Marshal(v)
+	if err != nil {
+		e.err = err
+		return e
+	}
+	e.status = status
+	e.respBody = body
+	e.header.Set("Content-Type", "application/json")
+	return e
+}
+
+// RespondHeader adds a header to the response
+func (e *Expectation) RespondHeader(name, value string) *Expectation {
+	e.header.Add(name, value)
+	return e
+}
+
+// RespondError makes the transport fail with err
+func (e *Expectation) RespondError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+// Delay waits before responding, or until the request is cancelled
+func (e *Expectation) Delay(d time.Duration) *Expectation {
+	e.delay = d
+	return e
+}
+
+// String describes the expectation
+func (e *Expectation) String() string {
+	method := e.method
+	if method == "" {
+		method = "*"
+	}
+	return method + " " + e.pattern
+}
+
+// remaining reports whether the expectation may answer another request
+func (e *Expectation) remaining() bool {
+	return e.times == 0 || e.calls < e.times
+}
+
+// matches reports whether req with the given body meets the expectation
+func (e *Expectation) matches(req *http.Request, body []byte) bool {
+	if e.method != "" && e.method != req.Method {
+		return false
+	}
+	target := req.URL.String()
+	if strings.HasPrefix(e.pattern, "/") {
+		target = req.URL.RequestURI()
+	}
+	if !e.url.MatchString(target) {
+		return false
+	}
+	for name, values := range e.headers {
+		for _, value := range values {
+			if !containsValue(req.Header.Values(name), value) {
+				return false
+			}
+		}
+	}
+	for _, match := range e.body {
+		if !match(body) {
+			return false
+		}
+	}
+	return true
+}
+
+// response builds the canned response for req
+func (e *Expectation) response(req *http.Request) (*http.Response, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.status, http.StatusText(e.status)),
+		StatusCode:    e.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.respBody)),
+		ContentLength: int64(len(e.respBody)),
+		Request:       req,
+	}, nil
+}
+
+// globPattern compiles a pattern where "*" matches any run of characters
+func globPattern(pattern string) *regexp.Regexp {
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$")
+}
+
+// normalizeJSON round-trips v through JSON so it compares equal to a
+// decoded body
+func normalizeJSON(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var normalized interface{}
+	err = json.Unmarshal(data, &normalized)
+	return normalized, err
+}
+
+// containsValue reports whether values contains value
+func containsValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// describe formats req for error messages
+func describe(req *http.Request) string {
+	return req.Method + " " + req.URL.String()
+}
@@ -0,0 +1,132 @@
+package httpclienttest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	httpclient "github.com/augustoberwaldt/go-request-client"
+)
+
+// recordingT collects the failures reported by AssertExpectations
+type recordingT struct {
+	errors []string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestMockTransport_Expectations(t *testing.T) {
+	mock := NewMockTransport()
+	mock.Expect("POST", "/users").
+		WithHeader("Authorization", "Bearer token").
+		WithJSONBody(map[string]interface{}{"name": "Ada", "age": 36}).
+		RespondJSON(http.StatusCreated, map[string]int{"id": 1})
+	mock.Expect("GET", "/users/*").
+		Respond(http.StatusOK, "user").
+		Times(2)
+
+	client := httpclient.NewClient(
+		httpclient.WithBaseURL("https://api.example.com"),
+		httpclient.WithHeaders(map[string]string{"Authorization": "Bearer token"}),
+		mock.Option(),
+	)
+
+	resp, err := client.Post("/users", &httpclient.RequestOptions{
+		JSON: map[string]interface{}{"age": 36, "name": "Ada"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if resp.GetStatusCode() != http.StatusCreated {
+		t.Errorf("Expected status 201, got %d", resp.GetStatusCode())
+	}
+	var created map[string]int
+	if err := resp.UnmarshalJSON(&created); err != nil || created["id"] != 1 {
+		t.Errorf("Expected id 1, got %v (%v)", created, err)
+	}
+
+	for _, id := range []string{"1", "2"} {
+		resp, err := client.Get("/users/"+id, nil)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if resp.GetBody() != "user" {
+			t.Errorf("Expected body 'user', got '%s'", resp.GetBody())
+		}
+	}
+
+	mock.AssertExpectations(t)
+	if len(mock.Requests()) != 3 {
+		t.Errorf("Expected 3 requests, got %d", len(mock.Requests()))
+	}
+}
+
+func TestMockTransport_Unmatched(t *testing.T) {
+	mock := NewMockTransport()
+	mock.Expect("POST", "https://api.example.com/users").
+		WithJSONField("user.name", "Ada")
+	mock.Expect("DELETE", "/users/1")
+
+	client := httpclient.NewClient(mock.Option())
+
+	_, err := client.Post("https://api.example.com/users", &httpclient.RequestOptions{
+		JSON: map[string]interface{}{"user": map[string]string{"name": "Bob"}},
+	})
+	if !errors.Is(err, ErrUnexpectedRequest) {
+		t.Errorf("Expected ErrUnexpectedRequest, got %v", err)
+	}
+
+	_, err = client.Post("https://api.example.com/users", &httpclient.RequestOptions{
+		JSON: map[string]interface{}{"user": map[string]string{"name": "Ada"}},
+	})
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	recorder := &recordingT{}
+	if mock.AssertExpectations(recorder) {
+		t.Error("Expected assertions to fail")
+	}
+	if len(recorder.errors) != 2 {
+		t.Fatalf("Expected 2 failures, got %v", recorder.errors)
+	}
+	if recorder.errors[0] != "httpclienttest: expected DELETE /users/1 to be called 1 times, got 0" {
+		t.Errorf("Unexpected failure: %s", recorder.errors[0])
+	}
+	if recorder.errors[1] != "httpclienttest: unexpected request POST https://api.example.com/users" {
+		t.Errorf("Unexpected failure: %s", recorder.errors[1])
+	}
+}
+
+func TestMockTransport_ErrorsAndDelays(t *testing.T) {
+	mock := NewMockTransport()
+	failure := errors.New("connection reset")
+	mock.Expect("GET", "/flaky").RespondError(failure)
+	mock.Expect("GET", "/slow").Delay(time.Second).AnyTimes()
+
+	client := httpclient.NewClient(httpclient.WithBaseURL("http://example.com"), mock.Option())
+
+	if _, err := client.Get("/flaky", nil); !errors.Is(err, failure) {
+		t.Errorf("Expected transport error, got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := client.RequestWithContext(ctx, "GET", "/slow", nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the delay to stop on cancellation, took %v", elapsed)
+	}
+
+	mock.AssertExpectations(t)
+}
@@ -0,0 +1,70 @@
+// Package redact hides secret values from logs and recorded cassettes
+package redact
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Redacted replaces secret values
+const Redacted = "[REDACTED]"
+
+// DefaultHeaders are always redacted
+var DefaultHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// Set is a lookup set of lower-cased names
+type Set map[string]bool
+
+// NewSet returns the lower-cased union of the lists
+func NewSet(lists ...[]string) Set {
+	set := make(Set)
+	for _, list := range lists {
+		for _, name := range list {
+			set[strings.ToLower(name)] = true
+		}
+	}
+	return set
+}
+
+// Has reports whether name is in the set, ignoring case
+func (s Set) Has(name string) bool {
+	return s[strings.ToLower(name)]
+}
+
+// JSONBody redacts the fields in the set at any depth of a JSON body.
+// Bodies that are not JSON according to header are returned unchanged.
+func (s Set) JSONBody(body []byte, header http.Header) []byte {
+	if len(s) == 0 || !strings.Contains(header.Get("Content-Type"), "json") {
+		return body
+	}
+
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return body
+	}
+	redactedBody, err := json.Marshal(s.JSON(data))
+	if err != nil {
+		return body
+	}
+	return redactedBody
+}
+
+// JSON replaces the values of the fields in the set at any depth of data
+func (s Set) JSON(data interface{}) interface{} {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if s.Has(key) {
+				v[key] = Redacted
+			} else {
+				v[key] = s.JSON(value)
+			}
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = s.JSON(value)
+		}
+	}
+	return data
+}
@@ -0,0 +1,202 @@
+package httpclient
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/augustoberwaldt/go-request-client/internal/redact"
+)
+
+// defaultRedactedQueryParams are always redacted by SlogMiddleware
+var defaultRedactedQueryParams = []string{"token", "access_token", "api_key", "password"}
+
+// SlogOptions configures SlogMiddleware
+type SlogOptions struct {
+	// Level is used for successful calls; failed calls are logged at
+	// slog.LevelError and 4xx/5xx responses at slog.LevelWarn
+	Level slog.Level
+
+	LogHeaders      bool
+	LogRequestBody  bool
+	LogResponseBody bool
+	MaxBodySize     int // bytes of each body to log, defaults to 4096
+
+	RedactHeaders     []string // in addition to Authorization and cookies
+	RedactJSONFields  []string
+	RedactQueryParams []string // in addition to token, access_token, api_key and password
+
+	// SuccessSampleRate is the fraction of successful calls that are
+	// logged, from 0 to 1; nil logs every call. Failures are always logged.
+	SuccessSampleRate *float64
+}
+
+// SampleRate returns a pointer to rate for SlogOptions.SuccessSampleRate
+func SampleRate(rate float64) *float64 {
+	return &rate
+}
+
+// SlogMiddleware logs every request as a structured log/slog record
+func SlogMiddleware(logger *slog.Logger, options *SlogOptions) Middleware {
+	if options == nil {
+		options = &SlogOptions{}
+	}
+	l := &slogLogger{
+		logger:      logger,
+		options:     options,
+		headers:     redact.NewSet(redact.DefaultHeaders, options.RedactHeaders),
+		jsonFields:  redact.NewSet(options.RedactJSONFields),
+		queryParams: redact.NewSet(defaultRedactedQueryParams, options.RedactQueryParams),
+		maxBodySize: options.MaxBodySize,
+	}
+	if l.maxBodySize <= 0 {
+		l.maxBodySize = 4096
+	}
+
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*Response, error) {
+			var reqBody []byte
+			if options.LogRequestBody {
+				reqBody = peekBody(req)
+			}
+
+			start := time.Now()
+			resp, err := next(req)
+			duration := time.Since(start)
+
+			l.log(req, reqBody, resp, err, duration)
+			return resp, err
+		}
+	}
+}
+
+// slogLogger holds the prepared state of a SlogMiddleware
+type slogLogger struct {
+	logger      *slog.Logger
+	options     *SlogOptions
+	headers     redact.Set
+	jsonFields  redact.Set
+	queryParams redact.Set
+	maxBodySize int
+}
+
+// log emits the record for a single call
+func (l *slogLogger) log(req *http.Request, reqBody []byte, resp *Response, err error, duration time.Duration) {
+	level := l.options.Level
+	switch {
+	case err != nil:
+		level = slog.LevelError
+	case resp.StatusCode >= 400:
+		level = slog.LevelWarn
+	case l.options.SuccessSampleRate != nil && rand.Float64() >= *l.options.SuccessSampleRate:
+		return
+	}
+
+	ctx := req.Context()
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("url", l.redactURL(req.URL)),
+		slog.String("route", routeOf(req)),
+		slog.Duration("duration", duration),
+		slog.Int("attempt", AttemptFromContext(ctx)),
+	}
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		attrs = append(attrs, slog.String("request_id", requestID))
+	}
+	if l.options.LogHeaders {
+		attrs = append(attrs, slog.Any("request_headers", l.headerGroup(req.Header)))
+	}
+	if reqBody != nil {
+		attrs = append(attrs, slog.String("request_body", l.formatBody(reqBody, req.Header)))
+	}
+
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+		l.logger.LogAttrs(ctx, level, "http request failed", attrs...)
+		return
+	}
+
+	attrs = append(attrs,
+		slog.Int("status", resp.StatusCode),
+		slog.Int("bytes", len(resp.Body)),
+	)
+	if l.options.LogHeaders {
+		attrs = append(attrs, slog.Any("response_headers", l.headerGroup(resp.Header)))
+	}
+	if l.options.LogResponseBody {
+		attrs = append(attrs, slog.String("response_body", l.formatBody(resp.Body, resp.Header)))
+	}
+
+	l.logger.LogAttrs(ctx, level, "http request", attrs...)
+}
+
+// redactURL returns the URL with secret query parameters redacted
+func (l *slogLogger) redactURL(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.String()
+	}
+
+	q := u.Query()
+	for name := range q {
+		if l.queryParams.Has(name) {
+			q.Set(name, redact.Redacted)
+		}
+	}
+
+	redactedURL := *u
+	redactedURL.RawQuery = q.Encode()
+	return redactedURL.String()
+}
+
+// headerGroup returns the headers as a slog group value
+func (l *slogLogger) headerGroup(header http.Header) slog.Value {
+	attrs := make([]slog.Attr, 0, len(header))
+	for name, values := range header {
+		value := strings.Join(values, ", ")
+		if l.headers.Has(name) {
+			value = redact.Redacted
+		}
+		attrs = append(attrs, slog.String(name, value))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// formatBody redacts JSON fields from body and truncates it
+func (l *slogLogger) formatBody(body []byte, header http.Header) string {
+	body = l.jsonFields.JSONBody(body, header)
+	if len(body) > l.maxBodySize {
+		return string(body[:l.maxBodySize]) + "...(truncated)"
+	}
+	return string(body)
+}
+
+// peekBody reads the request body without consuming it
+func peekBody(req *http.Request) []byte {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil
+		}
+		defer body.Close()
+		data, _ := io.ReadAll(body)
+		return data
+	}
+
+	data, _ := io.ReadAll(req.Body)
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	return data
+}
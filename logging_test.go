@@ -0,0 +1,103 @@
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSlogMiddleware(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=abc123")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id": 1, "password": "hunter2"}`))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	client := NewClient(
+		WithBaseURL(server.URL),
+		WithMiddleware(SlogMiddleware(logger, &SlogOptions{
+			LogHeaders:       true,
+			LogRequestBody:   true,
+			LogResponseBody:  true,
+			RedactJSONFields: []string{"password"},
+		})),
+	)
+
+	_, err := client.Post("/login", &RequestOptions{
+		Headers:     map[string]string{"Authorization": "Bearer secret-token"},
+		QueryParams: map[string]string{"token": "secret-token", "page": "1"},
+		JSON:        map[string]string{"username": "john", "password": "hunter2"},
+		Route:       "/login",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	output := buf.String()
+	if strings.Contains(output, "secret-token") || strings.Contains(output, "hunter2") || strings.Contains(output, "abc123") {
+		t.Errorf("Expected secrets to be redacted, got %s", output)
+	}
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Failed to decode log record: %v", err)
+	}
+
+	if record["method"] != "POST" || record["route"] != "/login" || record["status"] != float64(200) {
+		t.Errorf("Expected method, route and status fields, got %v", record)
+	}
+
+	if record["attempt"] != float64(1) {
+		t.Errorf("Expected attempt to be 1, got %v", record["attempt"])
+	}
+
+	if !strings.Contains(record["request_body"].(string), `"username":"john"`) {
+		t.Errorf("Expected request body to be logged, got %v", record["request_body"])
+	}
+}
+
+func TestSlogMiddleware_Sampling(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	client := NewClient(
+		WithBaseURL(server.URL),
+		WithMiddleware(SlogMiddleware(logger, &SlogOptions{SuccessSampleRate: SampleRate(0)})),
+	)
+
+	for i := 0; i < 10; i++ {
+		client.Get("/ok", nil)
+	}
+	client.Get("/fail", nil)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], `"status":500`) {
+		t.Errorf("Expected only the failed call to be logged, got %v", lines)
+	}
+
+	// Without a sample rate every call is logged
+	buf.Reset()
+	client = NewClient(WithBaseURL(server.URL), WithMiddleware(SlogMiddleware(logger, nil)))
+	for i := 0; i < 3; i++ {
+		client.Get("/ok", nil)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 3 {
+		t.Errorf("Expected every call to be logged, got %d lines", len(lines))
+	}
+}
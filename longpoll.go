@@ -0,0 +1,188 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxBackoffAttempt caps the attempt passed to a BackoffStrategy by loops
+// retrying forever, as ExponentialBackoff overflows on large attempts
+const maxBackoffAttempt = 16
+
+// LongPollOptions configures a long-poll loop
+type LongPollOptions struct {
+	// Method defaults to GET
+	Method string
+	// Request holds the options of every request; the cursor is added to
+	// its query parameters
+	Request *RequestOptions
+	// Param is the query parameter carrying the cursor, defaults to
+	// "cursor"; use "since" and the like as the API requires
+	Param string
+	// Cursor is the cursor of the first request; none is sent when empty
+	Cursor string
+	// NextCursor extracts the cursor of the next request from a response,
+	// defaults to CursorFromJSON("cursor"). An empty cursor keeps the
+	// previous one.
+	NextCursor func(*Response) string
+	// MinInterval is the minimum time between the start of two polls, so
+	// that a server answering right away, with 204, 304 or new data, is
+	// not flooded with requests. Zero polls again immediately.
+	MinInterval time.Duration
+	// Backoff is the delay after consecutive failures, defaults to an
+	// exponential backoff from 1s to 30s
+	Backoff BackoffStrategy
+	// OnError is called with every failed poll before backing off
+	OnError func(error)
+}
+
+// CursorFromHeader returns a cursor extractor reading a response header
+func CursorFromHeader(name string) func(*Response) string {
+	return func(resp *Response) string {
+		return resp.Header.Get(name)
+	}
+}
+
+// CursorFromJSON returns a cursor extractor reading a field of a JSON
+// response body. Nested fields are separated by dots, as in "meta.next".
+func CursorFromJSON(path string) func(*Response) string {
+	keys := strings.Split(path, ".")
+	return func(resp *Response) string {
+		decoder := json.NewDecoder(bytes.NewReader(resp.Body))
+		decoder.UseNumber()
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return ""
+		}
+		for _, key := range keys {
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return ""
+			}
+			value = object[key]
+		}
+		switch v := value.(type) {
+		case string:
+			return v
+		case json.Number:
+			return v.String()
+		case nil:
+			return ""
+		default:
+			return fmt.Sprint(v)
+		}
+	}
+}
+
+// LongPoll polls path, re-issuing the request as soon as the previous one
+// completes, or MinInterval after it started, and passes each response to
+// handle until ctx is done or handle returns an error, which LongPoll then
+// returns.
+//
+// Each request carries the cursor extracted from the previous response.
+// Responses with status 204 or 304 mean the server had nothing new and are
+// not handled. Request errors and other non-2xx responses are reported to
+// OnError and retried after the backoff delay. Make sure the client
+// timeout is longer than the time the server holds a poll.
+func (c *Client) LongPoll(ctx context.Context, path string, options *LongPollOptions, handle func(*Response) error) error {
+	if options == nil {
+		options = &LongPollOptions{}
+	}
+	method := options.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	param := options.Param
+	if param == "" {
+		param = "cursor"
+	}
+	nextCursor := options.NextCursor
+	if nextCursor == nil {
+		nextCursor = CursorFromJSON("cursor")
+	}
+	backoff := options.Backoff
+	if backoff == nil {
+		backoff = &ExponentialBackoff{BaseDelay: time.Second, MaxDelay: 30 * time.Second}
+	}
+
+	cursor := options.Cursor
+	failures := 0
+	var started time.Time
+	for {
+		if wait := options.MinInterval - time.Since(started); !started.IsZero() && wait > 0 {
+			if err := sleepContext(ctx, wait); err != nil {
+				return err
+			}
+		}
+		started = time.Now()
+
+		request := &RequestOptions{}
+		if options.Request != nil {
+			copied := *options.Request
+			request = &copied
+		}
+		if cursor != "" {
+			query := make(map[string]string, len(request.QueryParams)+1)
+			for k, v := range request.QueryParams {
+				query[k] = v
+			}
+			query[param] = cursor
+			request.QueryParams = query
+		}
+
+		resp, err := c.RequestWithContext(ctx, method, path, request)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err == nil && (resp.StatusCode < 200 || resp.StatusCode >= 300) && resp.StatusCode != http.StatusNotModified {
+			err = &StatusError{Response: resp}
+		}
+
+		if err != nil {
+			if options.OnError != nil {
+				options.OnError(err)
+			}
+			if err := sleepContext(ctx, backoff.Delay(failures)); err != nil {
+				return err
+			}
+			if failures < maxBackoffAttempt {
+				failures++
+			}
+			continue
+		}
+		failures = 0
+
+		if resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified {
+			continue
+		}
+		if next := nextCursor(resp); next != "" {
+			cursor = next
+		}
+		if err := handle(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// LongPollChannel runs LongPoll and delivers the responses on a channel,
+// which is closed once ctx is done
+func (c *Client) LongPollChannel(ctx context.Context, path string, options *LongPollOptions) <-chan *Response {
+	responses := make(chan *Response)
+	go func() {
+		defer close(responses)
+		c.LongPoll(ctx, path, options, func(resp *Response) error {
+			select {
+			case responses <- resp:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+	return responses
+}
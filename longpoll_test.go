@@ -0,0 +1,132 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_LongPoll(t *testing.T) {
+	var mu sync.Mutex
+	var cursors []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		cursors = append(cursors, r.URL.Query().Get("since"))
+		call := len(cursors)
+		mu.Unlock()
+
+		switch call {
+		case 2:
+			w.WriteHeader(http.StatusNoContent) // nothing new
+		case 3:
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			fmt.Fprintf(w, `{"events":["e%d"],"meta":{"next":%d}}`, call, call*10)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(WithBaseURL(server.URL))
+	var failures []error
+	var events []string
+	err := client.LongPoll(context.Background(), "/events", &LongPollOptions{
+		Request:    &RequestOptions{QueryParams: map[string]string{"topic": "orders"}},
+		Param:      "since",
+		Cursor:     "0",
+		NextCursor: CursorFromJSON("meta.next"),
+		Backoff:    &ExponentialBackoff{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+		OnError:    func(err error) { failures = append(failures, err) },
+	}, func(resp *Response) error {
+		var body struct{ Events []string }
+		resp.UnmarshalJSON(&body)
+		events = append(events, body.Events...)
+		if len(events) == 3 {
+			return errors.New("done")
+		}
+		return nil
+	})
+
+	if err == nil || err.Error() != "done" {
+		t.Errorf("Expected the handler error, got %v", err)
+	}
+	expected := []string{"0", "10", "10", "10", "40"}
+	if fmt.Sprint(cursors) != fmt.Sprint(expected) {
+		t.Errorf("Expected cursors %v, got %v", expected, cursors)
+	}
+	if fmt.Sprint(events) != "[e1 e4 e5]" {
+		t.Errorf("Expected events [e1 e4 e5], got %v", events)
+	}
+	var statusErr *StatusError
+	if len(failures) != 1 || !errors.As(failures[0], &statusErr) || statusErr.Response.StatusCode != http.StatusBadGateway {
+		t.Errorf("Expected a single 502 failure, got %v", failures)
+	}
+}
+
+func TestClient_LongPollChannel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cursor, _ := strconv.Atoi(r.URL.Query().Get("cursor"))
+		w.Header().Set("X-Next-Cursor", strconv.Itoa(cursor+1))
+		fmt.Fprint(w, cursor)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := NewClient(WithBaseURL(server.URL))
+	responses := client.LongPollChannel(ctx, "/feed", &LongPollOptions{
+		NextCursor: CursorFromHeader("X-Next-Cursor"),
+	})
+
+	var bodies []string
+	for resp := range responses {
+		bodies = append(bodies, resp.GetBody())
+		if len(bodies) == 3 {
+			cancel()
+		}
+	}
+	if fmt.Sprint(bodies[:3]) != "[0 1 2]" {
+		t.Errorf("Expected each request to carry the previous cursor, got %q", bodies)
+	}
+}
+
+func TestClient_LongPollMinInterval(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	client := NewClient(WithBaseURL(server.URL))
+	client.LongPoll(ctx, "/events", &LongPollOptions{MinInterval: 30 * time.Millisecond}, func(*Response) error {
+		return nil
+	})
+
+	if n := atomic.LoadInt32(&calls); n < 2 || n > 4 {
+		t.Errorf("Expected about one poll per 30ms, got %d in 100ms", n)
+	}
+}
+
+func TestCursorFromJSON(t *testing.T) {
+	resp := &Response{Body: []byte(`{"next":12345678901,"page":{"token":"abc"},"list":[1]}`)}
+
+	for path, expected := range map[string]string{
+		"next":       "12345678901",
+		"page.token": "abc",
+		"missing":    "",
+		"list.0":     "",
+	} {
+		if cursor := CursorFromJSON(path)(resp); cursor != expected {
+			t.Errorf("CursorFromJSON(%q) = %q, expected %q", path, cursor, expected)
+		}
+	}
+}
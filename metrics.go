@@ -0,0 +1,327 @@
+package httpclient
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MetricLabels identifies a series of request metrics
+type MetricLabels struct {
+	Host        string
+	Method      string
+	Route       string
+	StatusClass string // "2xx", "4xx", "error", ...; empty for in-flight requests
+}
+
+// MetricsCollector receives request metrics from MetricsMiddleware
+type MetricsCollector interface {
+	// RequestStarted is called before a request is sent
+	RequestStarted(labels MetricLabels)
+	// RequestFinished is called once a request completes or fails. The
+	// labels carry the status class of the outcome.
+	RequestFinished(labels MetricLabels, duration time.Duration, responseSize int)
+}
+
+// QueueCollector receives queueing metrics from a ConcurrencyLimiter
+type QueueCollector interface {
+	// QueueDepth is called when the number of requests waiting for key changes
+	QueueDepth(key string, depth int)
+	// QueueWait is called with the time a request waited for a slot of key
+	QueueWait(key string, wait time.Duration)
+}
+
+// MetricsMiddleware records request count, latency, in-flight requests and
+// response size into collector
+func MetricsMiddleware(collector MetricsCollector) Middleware {
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*Response, error) {
+			labels := MetricLabels{
+				Host:   req.URL.Host,
+				Method: req.Method,
+				Route:  routeLabelOf(req),
+			}
+			collector.RequestStarted(labels)
+
+			start := time.Now()
+			resp, err := next(req)
+			duration := time.Since(start)
+
+			size := 0
+			if err != nil {
+				labels.StatusClass = "error"
+			} else {
+				labels.StatusClass = statusClass(resp.StatusCode)
+				size = len(resp.Body)
+			}
+			collector.RequestFinished(labels, duration, size)
+
+			return resp, err
+		}
+	}
+}
+
+// statusClass returns the class of an HTTP status code, such as "2xx"
+func statusClass(code int) string {
+	if code < 100 || code > 599 {
+		return "unknown"
+	}
+	return strconv.Itoa(code/100) + "xx"
+}
+
+// DefaultDurationBuckets are the latency histogram buckets in seconds
+var DefaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// DefaultSizeBuckets are the response size histogram buckets in bytes
+var DefaultSizeBuckets = []float64{100, 1000, 10000, 100000, 1000000, 10000000}
+
+// MetricsOptions configures Metrics
+type MetricsOptions struct {
+	Namespace       string // prefix of every metric name
+	DurationBuckets []float64
+	SizeBuckets     []float64
+}
+
+// Metrics is an in-memory MetricsCollector and QueueCollector that can be
+// exposed in the Prometheus text format
+type Metrics struct {
+	mu              sync.Mutex
+	prefix          string
+	durationBuckets []float64
+	sizeBuckets     []float64
+	inFlight        map[MetricLabels]int64
+	requests        map[MetricLabels]uint64
+	durations       map[MetricLabels]*histogram
+	sizes           map[MetricLabels]*histogram
+	queueDepths     map[string]int
+	queueWaits      map[string]*histogram
+}
+
+// NewMetrics creates a new metrics collector
+func NewMetrics(options *MetricsOptions) *Metrics {
+	if options == nil {
+		options = &MetricsOptions{}
+	}
+
+	m := &Metrics{
+		prefix:          "http_client_",
+		durationBuckets: options.DurationBuckets,
+		sizeBuckets:     options.SizeBuckets,
+		inFlight:        make(map[MetricLabels]int64),
+		requests:        make(map[MetricLabels]uint64),
+		durations:       make(map[MetricLabels]*histogram),
+		sizes:           make(map[MetricLabels]*histogram),
+		queueDepths:     make(map[string]int),
+		queueWaits:      make(map[string]*histogram),
+	}
+	if options.Namespace != "" {
+		m.prefix = options.Namespace + "_" + m.prefix
+	}
+	if m.durationBuckets == nil {
+		m.durationBuckets = DefaultDurationBuckets
+	}
+	if m.sizeBuckets == nil {
+		m.sizeBuckets = DefaultSizeBuckets
+	}
+
+	return m
+}
+
+// RequestStarted implements MetricsCollector
+func (m *Metrics) RequestStarted(labels MetricLabels) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	labels.StatusClass = ""
+	m.inFlight[labels]++
+}
+
+// RequestFinished implements MetricsCollector
+func (m *Metrics) RequestFinished(labels MetricLabels, duration time.Duration, responseSize int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	inFlight := labels
+	inFlight.StatusClass = ""
+	m.inFlight[inFlight]--
+
+	m.requests[labels]++
+
+	if m.durations[labels] == nil {
+		m.durations[labels] = newHistogram(m.durationBuckets)
+	}
+	m.durations[labels].observe(duration.Seconds())
+
+	if m.sizes[labels] == nil {
+		m.sizes[labels] = newHistogram(m.sizeBuckets)
+	}
+	m.sizes[labels].observe(float64(responseSize))
+}
+
+// QueueDepth implements QueueCollector
+func (m *Metrics) QueueDepth(key string, depth int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.queueDepths[key] = depth
+}
+
+// QueueWait implements QueueCollector
+func (m *Metrics) QueueWait(key string, wait time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.queueWaits[key] == nil {
+		m.queueWaits[key] = newHistogram(m.durationBuckets)
+	}
+	m.queueWaits[key].observe(wait.Seconds())
+}
+
+// WritePrometheus writes all metrics in the Prometheus text exposition format
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+
+	name := m.prefix + "requests_total"
+	fmt.Fprintf(bw, "# HELP %s Total number of outbound HTTP requests.\n", name)
+	fmt.Fprintf(bw, "# TYPE %s counter\n", name)
+	for _, labels := range sortedLabels(m.requests) {
+		fmt.Fprintf(bw, "%s%s %d\n", name, formatLabels(labels), m.requests[labels])
+	}
+
+	name = m.prefix + "requests_in_flight"
+	fmt.Fprintf(bw, "# HELP %s Number of outbound HTTP requests in flight.\n", name)
+	fmt.Fprintf(bw, "# TYPE %s gauge\n", name)
+	for _, labels := range sortedLabels(m.inFlight) {
+		fmt.Fprintf(bw, "%s%s %d\n", name, formatLabels(labels), m.inFlight[labels])
+	}
+
+	writeHistograms(bw, m.prefix+"request_duration_seconds", "Outbound HTTP request latency in seconds.", m.durations)
+	writeHistograms(bw, m.prefix+"response_size_bytes", "Outbound HTTP response body size in bytes.", m.sizes)
+
+	if len(m.queueWaits) > 0 {
+		name = m.prefix + "queue_depth"
+		fmt.Fprintf(bw, "# HELP %s Number of outbound HTTP requests waiting for a concurrency slot.\n", name)
+		fmt.Fprintf(bw, "# TYPE %s gauge\n", name)
+		for _, key := range sortedKeys(m.queueDepths) {
+			fmt.Fprintf(bw, "%s{%s} %d\n", name, labelPair("key", key), m.queueDepths[key])
+		}
+
+		name = m.prefix + "queue_wait_seconds"
+		fmt.Fprintf(bw, "# HELP %s Time outbound HTTP requests waited for a concurrency slot in seconds.\n", name)
+		fmt.Fprintf(bw, "# TYPE %s histogram\n", name)
+		for _, key := range sortedKeys(m.queueWaits) {
+			writeHistogram(bw, name, "{"+labelPair("key", key)+"}", m.queueWaits[key])
+		}
+	}
+
+	return bw.Flush()
+}
+
+// ServeHTTP exposes the metrics so Metrics can be mounted as a /metrics handler
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WritePrometheus(w)
+}
+
+// histogram is a cumulative Prometheus-style histogram
+type histogram struct {
+	bounds []float64
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// newHistogram creates a histogram with the given upper bounds
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{
+		bounds: bounds,
+		counts: make([]uint64, len(bounds)),
+	}
+}
+
+// observe records a single value
+func (h *histogram) observe(value float64) {
+	for i, bound := range h.bounds {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+// writeHistograms writes a family of histograms
+func writeHistograms(w io.Writer, name, help string, histograms map[MetricLabels]*histogram) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s histogram\n", name)
+	for _, labels := range sortedLabels(histograms) {
+		writeHistogram(w, name, formatLabels(labels), histograms[labels])
+	}
+}
+
+// writeHistogram writes the series of a single histogram with the
+// rendered label set base
+func writeHistogram(w io.Writer, name, base string, h *histogram) {
+	for i, bound := range h.bounds {
+		le := strconv.FormatFloat(bound, 'g', -1, 64)
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLabel(base, "le", le), h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLabel(base, "le", "+Inf"), h.count)
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, base, strconv.FormatFloat(h.sum, 'g', -1, 64))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, base, h.count)
+}
+
+// sortedLabels returns the keys of a series map in a stable order
+func sortedLabels[V any](series map[MetricLabels]V) []MetricLabels {
+	keys := make([]MetricLabels, 0, len(series))
+	for labels := range series {
+		keys = append(keys, labels)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return formatLabels(keys[i]) < formatLabels(keys[j])
+	})
+	return keys
+}
+
+// sortedKeys returns the keys of a map in order
+func sortedKeys[V any](series map[string]V) []string {
+	keys := make([]string, 0, len(series))
+	for key := range series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatLabels renders labels as {host="...",method="...",...}
+func formatLabels(labels MetricLabels) string {
+	pairs := []string{
+		labelPair("host", labels.Host),
+		labelPair("method", labels.Method),
+		labelPair("route", labels.Route),
+	}
+	if labels.StatusClass != "" {
+		pairs = append(pairs, labelPair("status_class", labels.StatusClass))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// withLabel appends a label pair to a rendered label set
+func withLabel(base, name, value string) string {
+	return strings.TrimSuffix(base, "}") + "," + labelPair(name, value) + "}"
+}
+
+// labelPair renders a single escaped label pair
+func labelPair(name, value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+	return name + `="` + value + `"`
+}
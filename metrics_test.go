@@ -0,0 +1,76 @@
+package httpclient
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/users/2" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id": 1}`))
+	}))
+	defer server.Close()
+
+	metrics := NewMetrics(&MetricsOptions{Namespace: "app"})
+	client := NewClient(WithBaseURL(server.URL), WithMiddleware(MetricsMiddleware(metrics)))
+
+	client.Get("/users/1", &RequestOptions{Route: "/users/{id}"})
+	client.Get("/users/1", &RequestOptions{Route: "/users/{id}"})
+	client.Get("/users/2", &RequestOptions{Route: "/users/{id}"})
+	client.Get("/users/3/avatar", nil)
+	client.Get("/users/4/avatar", nil)
+
+	var buf bytes.Buffer
+	if err := metrics.WritePrometheus(&buf); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	output := buf.String()
+
+	host := mustParseURL(t, server.URL).Host
+	expected := []string{
+		`# TYPE app_http_client_requests_total counter`,
+		`app_http_client_requests_total{host="` + host + `",method="GET",route="/users/{id}",status_class="2xx"} 2`,
+		`app_http_client_requests_total{host="` + host + `",method="GET",route="/users/{id}",status_class="4xx"} 1`,
+		`app_http_client_requests_total{host="` + host + `",method="GET",route="other",status_class="2xx"} 2`,
+		`app_http_client_requests_in_flight{host="` + host + `",method="GET",route="/users/{id}"} 0`,
+		`# TYPE app_http_client_request_duration_seconds histogram`,
+		`app_http_client_request_duration_seconds_count{host="` + host + `",method="GET",route="/users/{id}",status_class="2xx"} 2`,
+		`app_http_client_response_size_bytes_bucket{host="` + host + `",method="GET",route="/users/{id}",status_class="2xx",le="100"} 2`,
+		`app_http_client_response_size_bytes_sum{host="` + host + `",method="GET",route="/users/{id}",status_class="2xx"} 18`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(output, line+"\n") {
+			t.Errorf("Expected output to contain '%s', got:\n%s", line, output)
+		}
+	}
+}
+
+func TestMetricsMiddleware_Error(t *testing.T) {
+	metrics := NewMetrics(nil)
+	client := NewClient(WithMiddleware(MetricsMiddleware(metrics)))
+
+	client.Get("http://127.0.0.1:0/unreachable", nil)
+
+	var buf bytes.Buffer
+	metrics.WritePrometheus(&buf)
+	if !strings.Contains(buf.String(), `status_class="error"} 1`) {
+		t.Errorf("Expected failed request to be counted, got:\n%s", buf.String())
+	}
+}
+
+func mustParseURL(t *testing.T, rawURL string) *url.URL {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %v", err)
+	}
+	return u
+}
@@ -0,0 +1,80 @@
+package httpclient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_RetryMiddleware(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"name":"John"}` {
+			t.Errorf("Expected body to be resent on attempt %d, got '%s'", calls, body)
+		}
+		if calls < 3 {
+			hj, _ := w.(http.Hijacker)
+			conn, _, _ := hj.Hijack()
+			conn.Close()
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	attempts := 0
+	client := NewClient(
+		WithBaseURL(server.URL),
+		WithMiddleware(
+			RetryMiddleware(2, &ExponentialBackoff{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}),
+			func(next Handler) Handler {
+				return func(req *http.Request) (*Response, error) {
+					attempts = AttemptFromContext(req.Context())
+					return next(req)
+				}
+			},
+		),
+	)
+
+	resp, err := client.Post("/users", &RequestOptions{JSON: map[string]string{"name": "John"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if resp.GetStatusCode() != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.GetStatusCode())
+	}
+
+	if calls != 3 || attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d calls and attempt %d", calls, attempts)
+	}
+}
+
+func TestClient_RetryMiddlewareStreamedBody(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		hj, _ := w.(http.Hijacker)
+		conn, _, _ := hj.Hijack()
+		conn.Close()
+	}))
+	defer server.Close()
+
+	client := NewClient(
+		WithBaseURL(server.URL),
+		WithMiddleware(RetryMiddleware(2, &ExponentialBackoff{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond})),
+	)
+
+	if _, err := client.Post("/upload", &RequestOptions{Body: io.MultiReader(strings.NewReader("data"))}); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("Expected a body that cannot be replayed not to be retried, got %d calls", calls)
+	}
+}
@@ -0,0 +1,545 @@
+package httpclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrJobNotFound is returned for an unknown outbox job ID
+var ErrJobNotFound = errors.New("httpclient: outbox job not found")
+
+// OutboxJob is a request persisted by an Outbox until it is delivered
+type OutboxJob struct {
+	ID          string            `json:"id"`
+	Method      string            `json:"method"`
+	Path        string            `json:"path"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	QueryParams map[string]string `json:"query_params,omitempty"`
+	Body        []byte            `json:"body,omitempty"`
+	Auth        *Auth             `json:"auth,omitempty"`
+	Cookies     []*http.Cookie    `json:"cookies,omitempty"`
+	Timeout     time.Duration     `json:"timeout,omitempty"`
+	Route       string            `json:"route,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	Attempts    int               `json:"attempts"`
+	NextAttempt time.Time         `json:"next_attempt"`
+	LastError   string            `json:"last_error,omitempty"`
+}
+
+// ScheduleBackoff is a BackoffStrategy following a fixed retry schedule;
+// attempts beyond the schedule reuse its last delay
+type ScheduleBackoff []time.Duration
+
+// Delay implements BackoffStrategy
+func (s ScheduleBackoff) Delay(attempt int) time.Duration {
+	if len(s) == 0 {
+		return 0
+	}
+	if attempt >= len(s) {
+		attempt = len(s) - 1
+	}
+	return s[attempt]
+}
+
+// OutboxOptions configures an Outbox
+type OutboxOptions struct {
+	// MaxAttempts before a job is moved to the dead letters, defaults to 5
+	MaxAttempts int
+	// Backoff is the delay before each retry, defaults to an exponential
+	// backoff from 1s to 1h
+	Backoff BackoffStrategy
+	// PollInterval is how often Run looks for due jobs, defaults to 1s
+	PollInterval time.Duration
+	// Now returns the current time, defaults to time.Now
+	Now func() time.Time
+}
+
+// Outbox persists requests to a directory and delivers them through a
+// Client until they succeed, surviving process restarts.
+//
+// Jobs are kept in an append-only log, outbox.log, compacted once most of
+// it is obsolete. A 2xx response delivers a job. Other outcomes are
+// retried after the backoff delay, except 4xx responses other than 408,
+// 425 and 429, which are permanent. Jobs failing permanently or
+// MaxAttempts times are moved to dead-letter.log. Lines of a log that cannot
+// be read, such as one torn by a crash, are moved to a .corrupt file next
+// to it when the outbox is opened.
+type Outbox struct {
+	client  *Client
+	options OutboxOptions
+
+	mu          sync.Mutex
+	jobs        *outboxLog
+	deadLetters *outboxLog
+	delivering  sync.Mutex
+}
+
+// OpenOutbox opens the outbox stored in dir, creating it if needed
+func OpenOutbox(dir string, client *Client, options *OutboxOptions) (*Outbox, error) {
+	o := &Outbox{client: client}
+	if options != nil {
+		o.options = *options
+	}
+	if o.options.MaxAttempts <= 0 {
+		o.options.MaxAttempts = 5
+	}
+	if o.options.Backoff == nil {
+		o.options.Backoff = &ExponentialBackoff{BaseDelay: time.Second, MaxDelay: time.Hour}
+	}
+	if o.options.PollInterval <= 0 {
+		o.options.PollInterval = time.Second
+	}
+	if o.options.Now == nil {
+		o.options.Now = time.Now
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	var err error
+	if o.jobs, err = openOutboxLog(filepath.Join(dir, "outbox.log")); err != nil {
+		return nil, err
+	}
+	if o.deadLetters, err = openOutboxLog(filepath.Join(dir, "dead-letter.log")); err != nil {
+		o.jobs.close()
+		return nil, err
+	}
+	return o, nil
+}
+
+// Enqueue persists req for delivery and returns the ID of its job. The
+// method, path, headers, query parameters, body, auth, cookies, timeout and
+// route of req are stored in the outbox directory, credentials included;
+// the client defaults such as its base URL apply at delivery.
+func (o *Outbox) Enqueue(req ConcurrentRequest) (string, error) {
+	options := req.Options
+	if options == nil {
+		options = &RequestOptions{}
+	}
+	body, contentType, err := o.client.prepareBody(options)
+	if err != nil {
+		return "", err
+	}
+
+	now := o.options.Now()
+	job := &OutboxJob{
+		ID:          NewRequestID(),
+		Method:      req.Method,
+		Path:        req.Path,
+		Headers:     copyStringMap(options.Headers),
+		QueryParams: copyStringMap(options.QueryParams),
+		Timeout:     options.Timeout,
+		Route:       options.Route,
+		CreatedAt:   now,
+		NextAttempt: now,
+	}
+	if options.Auth != nil {
+		auth := *options.Auth
+		job.Auth = &auth
+	}
+	for _, cookie := range options.Cookies {
+		copied := *cookie
+		job.Cookies = append(job.Cookies, &copied)
+	}
+	if body != nil {
+		if job.Body, err = io.ReadAll(body); err != nil {
+			return "", err
+		}
+	}
+	if contentType != "" {
+		if job.Headers == nil {
+			job.Headers = make(map[string]string)
+		}
+		if _, ok := job.Headers["Content-Type"]; !ok {
+			job.Headers["Content-Type"] = contentType
+		}
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err := o.jobs.put(job); err != nil {
+		return "", err
+	}
+	return job.ID, nil
+}
+
+// Pending returns the jobs waiting for delivery, oldest first
+func (o *Outbox) Pending() []OutboxJob {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.jobs.list()
+}
+
+// DeadLetters returns the jobs that could not be delivered, oldest first
+func (o *Outbox) DeadLetters() []OutboxJob {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.deadLetters.list()
+}
+
+// Requeue moves a dead letter back to the outbox with its attempts reset
+func (o *Outbox) Requeue(id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	job, ok := o.deadLetters.jobs[id]
+	if !ok {
+		return ErrJobNotFound
+	}
+	requeued := *job
+	requeued.Attempts = 0
+	requeued.NextAttempt = o.options.Now()
+	if err := o.jobs.put(&requeued); err != nil {
+		return err
+	}
+	return o.deadLetters.delete(id)
+}
+
+// Discard removes a dead letter for good
+func (o *Outbox) Discard(id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if _, ok := o.deadLetters.jobs[id]; !ok {
+		return ErrJobNotFound
+	}
+	return o.deadLetters.delete(id)
+}
+
+// DeliverDue attempts every job whose next attempt is due and returns the
+// number of jobs delivered
+func (o *Outbox) DeliverDue(ctx context.Context) (int, error) {
+	o.delivering.Lock()
+	defer o.delivering.Unlock()
+
+	o.mu.Lock()
+	now := o.options.Now()
+	var due []OutboxJob
+	for _, job := range o.jobs.list() {
+		if !job.NextAttempt.After(now) {
+			due = append(due, job)
+		}
+	}
+	o.mu.Unlock()
+
+	delivered := 0
+	for _, job := range due {
+		if ctx.Err() != nil {
+			return delivered, ctx.Err()
+		}
+		options := &RequestOptions{
+			Headers:     job.Headers,
+			QueryParams: job.QueryParams,
+			Auth:        job.Auth,
+			Cookies:     job.Cookies,
+			Timeout:     job.Timeout,
+			Route:       job.Route,
+		}
+		if len(job.Body) > 0 {
+			options.Body = bytes.NewReader(job.Body)
+		}
+		resp, err := o.client.RequestWithContext(ctx, job.Method, job.Path, options)
+		if err != nil && ctx.Err() != nil {
+			// Shutting down is not a failed attempt
+			return delivered, ctx.Err()
+		}
+
+		ok, recordErr := o.record(job, resp, err)
+		if recordErr != nil {
+			return delivered, recordErr
+		}
+		if ok {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
+
+// Run delivers due jobs every PollInterval until ctx is done
+func (o *Outbox) Run(ctx context.Context) error {
+	ticker := time.NewTicker(o.options.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := o.DeliverDue(ctx); err != nil && ctx.Err() == nil {
+			return err
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Compact rewrites the logs with only the current jobs
+func (o *Outbox) Compact() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if err := o.jobs.compact(); err != nil {
+		return err
+	}
+	return o.deadLetters.compact()
+}
+
+// Close closes the log files
+func (o *Outbox) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	err := o.jobs.close()
+	if deadErr := o.deadLetters.close(); err == nil {
+		err = deadErr
+	}
+	return err
+}
+
+// record persists the outcome of an attempt and reports whether the job
+// was delivered
+func (o *Outbox) record(job OutboxJob, resp *Response, err error) (bool, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if _, ok := o.jobs.jobs[job.ID]; !ok {
+		return false, nil // removed while in flight
+	}
+	if err == nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return true, o.jobs.delete(job.ID)
+	}
+
+	job.Attempts++
+	permanent := false
+	if err != nil {
+		job.LastError = err.Error()
+	} else {
+		job.LastError = resp.Status
+		permanent = resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+			resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooEarly &&
+			resp.StatusCode != http.StatusTooManyRequests
+	}
+
+	if permanent || job.Attempts >= o.options.MaxAttempts {
+		if err := o.deadLetters.put(&job); err != nil {
+			return false, err
+		}
+		return false, o.jobs.delete(job.ID)
+	}
+	job.NextAttempt = o.options.Now().Add(o.options.Backoff.Delay(job.Attempts - 1))
+	return false, o.jobs.put(&job)
+}
+
+// outboxRecord is a line of an outbox log
+type outboxRecord struct {
+	Op  string     `json:"op"` // "put" or "delete"
+	Job *OutboxJob `json:"job,omitempty"`
+	ID  string     `json:"id,omitempty"`
+}
+
+// outboxLog is a set of jobs persisted as an append-only log of records
+type outboxLog struct {
+	path    string
+	file    *os.File
+	jobs    map[string]*OutboxJob
+	records int
+}
+
+// compactThreshold is the number of records below which a log is never
+// compacted automatically
+const compactThreshold = 1000
+
+// openOutboxLog replays the log at path. Lines that cannot be read are
+// skipped and moved to the quarantine file of the log.
+func openOutboxLog(path string) (*outboxLog, error) {
+	l := &outboxLog{path: path, jobs: make(map[string]*OutboxJob)}
+
+	var corrupt [][]byte
+	f, err := os.Open(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(nil, 64<<20)
+		for scanner.Scan() {
+			var record outboxRecord
+			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+				corrupt = append(corrupt, append([]byte(nil), scanner.Bytes()...))
+				continue
+			}
+			l.apply(record)
+			l.records++
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(corrupt) > 0 {
+		if err := quarantine(path+".corrupt", corrupt); err != nil {
+			return nil, fmt.Errorf("httpclient: quarantining %d corrupt lines of %s: %w", len(corrupt), path, err)
+		}
+	}
+	if l.file, err = os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644); err != nil {
+		return nil, err
+	}
+	if len(corrupt) > 0 {
+		// Drop the corrupt lines so that new records start on a line of
+		// their own
+		if err := l.compact(); err != nil {
+			l.close()
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// quarantine appends lines to the file at path durably
+func quarantine(path string, lines [][]byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		if _, err = f.Write(append(line, '\n')); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// apply updates the jobs with a record
+func (l *outboxLog) apply(record outboxRecord) {
+	switch record.Op {
+	case "put":
+		if record.Job != nil {
+			l.jobs[record.Job.ID] = record.Job
+		}
+	case "delete":
+		delete(l.jobs, record.ID)
+	}
+}
+
+// put stores job
+func (l *outboxLog) put(job *OutboxJob) error {
+	stored := *job
+	return l.append(outboxRecord{Op: "put", Job: &stored})
+}
+
+// delete removes the job with id
+func (l *outboxLog) delete(id string) error {
+	return l.append(outboxRecord{Op: "delete", ID: id})
+}
+
+// append writes a record durably, applies it and compacts the log once
+// most of its records are obsolete
+func (l *outboxLog) append(record outboxRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	if err := l.file.Sync(); err != nil {
+		return err
+	}
+	l.apply(record)
+	l.records++
+
+	if l.records > compactThreshold && l.records > 2*len(l.jobs) {
+		return l.compact()
+	}
+	return nil
+}
+
+// compact replaces the log with a put record per current job
+func (l *outboxLog) compact() error {
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), ".outbox-*")
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(tmp)
+	jobs := l.list()
+	for i := range jobs {
+		var data []byte
+		if data, err = json.Marshal(outboxRecord{Op: "put", Job: &jobs[i]}); err != nil {
+			break
+		}
+		w.Write(append(data, '\n'))
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), l.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	l.file.Close()
+	if l.file, err = os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND, 0o644); err != nil {
+		return err
+	}
+	l.records = len(jobs)
+	return nil
+}
+
+// list returns copies of the jobs, oldest first
+func (l *outboxLog) list() []OutboxJob {
+	jobs := make([]OutboxJob, 0, len(l.jobs))
+	for _, job := range l.jobs {
+		jobs = append(jobs, *job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].CreatedAt.Equal(jobs[j].CreatedAt) {
+			return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+		}
+		return jobs[i].ID < jobs[j].ID
+	})
+	return jobs
+}
+
+// close closes the log file
+func (l *outboxLog) close() error {
+	return l.file.Close()
+}
+
+// copyStringMap returns a copy of m, or nil when it is empty
+func copyStringMap(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	copied := make(map[string]string, len(m))
+	for k, v := range m {
+		copied[k] = v
+	}
+	return copied
+}
@@ -0,0 +1,290 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeClock is an adjustable clock for outbox tests
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestOutbox_DeliverAndReopen(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, r.Method+" "+r.URL.RequestURI()+" "+r.Header.Get("Content-Type")+" "+string(body))
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	client := NewClient(WithBaseURL(server.URL))
+	outbox, err := OpenOutbox(dir, client, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	id, err := outbox.Enqueue(ConcurrentRequest{
+		Method:  http.MethodPost,
+		Path:    "/events",
+		Options: &RequestOptions{JSON: map[string]string{"name": "signup"}, QueryParams: map[string]string{"v": "1"}},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	outbox.Close()
+
+	// The job survives a restart
+	outbox, err = OpenOutbox(dir, client, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer outbox.Close()
+	pending := outbox.Pending()
+	if len(pending) != 1 || pending[0].ID != id {
+		t.Fatalf("Expected job %s to be pending, got %+v", id, pending)
+	}
+
+	delivered, err := outbox.DeliverDue(context.Background())
+	if err != nil || delivered != 1 {
+		t.Fatalf("Expected 1 delivered job, got %d, %v", delivered, err)
+	}
+	expected := `POST /events?v=1 application/json {"name":"signup"}`
+	if len(bodies) != 1 || strings.TrimSpace(bodies[0]) != expected {
+		t.Errorf("Expected %q, got %q", expected, bodies)
+	}
+	if len(outbox.Pending()) != 0 {
+		t.Errorf("Expected no pending jobs, got %d", len(outbox.Pending()))
+	}
+}
+
+func TestOutbox_RequestOptions(t *testing.T) {
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	var route string
+	client := NewClient(WithBaseURL(server.URL), WithMiddleware(func(next Handler) Handler {
+		return func(req *http.Request) (*Response, error) {
+			route = RouteFromContext(req.Context())
+			return next(req)
+		}
+	}))
+	outbox, err := OpenOutbox(dir, client, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	outbox.Enqueue(ConcurrentRequest{Method: http.MethodPost, Path: "/orders/1", Options: &RequestOptions{
+		Auth:    &Auth{Username: "shop", Password: "secret"},
+		Cookies: []*http.Cookie{{Name: "session", Value: "abc"}},
+		Timeout: 5 * time.Second,
+		Route:   "/orders/{id}",
+	}})
+	outbox.Close()
+
+	outbox, err = OpenOutbox(dir, client, nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer outbox.Close()
+	if job := outbox.Pending()[0]; job.Timeout != 5*time.Second || job.Auth == nil || len(job.Cookies) != 1 {
+		t.Errorf("Expected the request options to survive a restart, got %+v", job)
+	}
+
+	if delivered, err := outbox.DeliverDue(context.Background()); err != nil || delivered != 1 {
+		t.Fatalf("Expected 1 delivered job, got %d, %v", delivered, err)
+	}
+	if username, password, ok := (&http.Request{Header: header}).BasicAuth(); !ok || username != "shop" || password != "secret" {
+		t.Errorf("Expected the auth to be delivered, got %q", header.Get("Authorization"))
+	}
+	if header.Get("Cookie") != "session=abc" || route != "/orders/{id}" {
+		t.Errorf("Expected the cookie and route to be delivered, got %q and %q", header.Get("Cookie"), route)
+	}
+}
+
+func TestOutbox_RetryAndDeadLetter(t *testing.T) {
+	var calls int32
+	server := newCountingServer(&calls, http.StatusServiceUnavailable)
+	defer server.Close()
+
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	client := NewClient(WithBaseURL(server.URL))
+	outbox, err := OpenOutbox(t.TempDir(), client, &OutboxOptions{
+		MaxAttempts: 3,
+		Backoff:     ScheduleBackoff{time.Minute, 10 * time.Minute},
+		Now:         clock.Now,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer outbox.Close()
+
+	id, _ := outbox.Enqueue(ConcurrentRequest{Method: http.MethodPost, Path: "/hook"})
+	ctx := context.Background()
+
+	outbox.DeliverDue(ctx)
+	job := outbox.Pending()[0]
+	if job.Attempts != 1 || !job.NextAttempt.Equal(clock.Now().Add(time.Minute)) {
+		t.Errorf("Expected a retry in 1m after 1 attempt, got %d at %v", job.Attempts, job.NextAttempt)
+	}
+	if job.LastError != "503 Service Unavailable" {
+		t.Errorf("Expected the last error to be recorded, got %q", job.LastError)
+	}
+
+	// Not due yet
+	outbox.DeliverDue(ctx)
+	if atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("Expected 1 call before the backoff elapsed, got %d", calls)
+	}
+
+	clock.Advance(time.Minute)
+	outbox.DeliverDue(ctx)
+	if job = outbox.Pending()[0]; !job.NextAttempt.Equal(clock.Now().Add(10 * time.Minute)) {
+		t.Errorf("Expected a retry in 10m, got %v", job.NextAttempt)
+	}
+
+	clock.Advance(10 * time.Minute)
+	outbox.DeliverDue(ctx)
+	if atomic.LoadInt32(&calls) != 3 {
+		t.Errorf("Expected 3 calls, got %d", calls)
+	}
+	if len(outbox.Pending()) != 0 {
+		t.Errorf("Expected the exhausted job to leave the outbox")
+	}
+	dead := outbox.DeadLetters()
+	if len(dead) != 1 || dead[0].ID != id || dead[0].Attempts != 3 {
+		t.Fatalf("Expected job %s in the dead letters after 3 attempts, got %+v", id, dead)
+	}
+
+	if err := outbox.Requeue("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Expected ErrJobNotFound, got %v", err)
+	}
+	if err := outbox.Requeue(id); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(outbox.DeadLetters()) != 0 {
+		t.Errorf("Expected the requeued job to leave the dead letters")
+	}
+	if job = outbox.Pending()[0]; job.Attempts != 0 || job.NextAttempt.After(clock.Now()) {
+		t.Errorf("Expected the requeued job to be due with no attempts, got %+v", job)
+	}
+}
+
+func TestOutbox_PermanentFailure(t *testing.T) {
+	var calls int32
+	server := newCountingServer(&calls, http.StatusBadRequest)
+	defer server.Close()
+
+	outbox, err := OpenOutbox(t.TempDir(), NewClient(WithBaseURL(server.URL)), nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer outbox.Close()
+
+	outbox.Enqueue(ConcurrentRequest{Method: http.MethodPost, Path: "/hook"})
+	outbox.DeliverDue(context.Background())
+
+	if atomic.LoadInt32(&calls) != 1 || len(outbox.DeadLetters()) != 1 {
+		t.Errorf("Expected a 400 to dead-letter the job at once, got %d calls and %d dead letters", calls, len(outbox.DeadLetters()))
+	}
+}
+
+func TestOutbox_Compaction(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	outbox, err := OpenOutbox(dir, NewClient(WithBaseURL(server.URL)), nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for i := 0; i < 20; i++ {
+		outbox.Enqueue(ConcurrentRequest{Method: http.MethodPost, Path: "/hook"})
+	}
+	outbox.DeliverDue(context.Background())
+	keep, _ := outbox.Enqueue(ConcurrentRequest{Method: http.MethodPost, Path: "/later"})
+
+	if err := outbox.Compact(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "outbox.log"))
+	if lines := strings.Count(string(data), "\n"); lines != 1 {
+		t.Errorf("Expected 1 record after compaction, got %d", lines)
+	}
+
+	// Appends keep working after compaction, and a torn write is dropped
+	outbox.Enqueue(ConcurrentRequest{Method: http.MethodPost, Path: "/after"})
+	outbox.Close()
+	f, _ := os.OpenFile(filepath.Join(dir, "outbox.log"), os.O_WRONLY|os.O_APPEND, 0o644)
+	f.WriteString(`{"op":"put","job":{"id":`)
+	f.Close()
+
+	outbox, err = OpenOutbox(dir, NewClient(WithBaseURL(server.URL)), nil)
+	if err != nil {
+		t.Fatalf("Expected a torn final record to be tolerated, got %v", err)
+	}
+	defer outbox.Close()
+	outbox.Enqueue(ConcurrentRequest{Method: http.MethodPost, Path: "/reopened"})
+
+	pending := outbox.Pending()
+	if len(pending) != 3 || pending[0].ID != keep {
+		t.Errorf("Expected 3 pending jobs starting with %s, got %+v", keep, pending)
+	}
+}
+
+func TestOutbox_CorruptLine(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "outbox.log")
+	garbage := `{"op":"put","job":{"id":"b",`
+	os.WriteFile(path, []byte(
+		`{"op":"put","job":{"id":"a","method":"POST","path":"/a"}}`+"\n"+
+			garbage+"\n"+
+			`{"op":"put","job":{"id":"c","method":"POST","path":"/c"}}`+"\n"), 0o644)
+
+	outbox, err := OpenOutbox(dir, NewClient(), nil)
+	if err != nil {
+		t.Fatalf("Expected a corrupt line to be skipped, got %v", err)
+	}
+	defer outbox.Close()
+
+	if pending := outbox.Pending(); len(pending) != 2 || pending[0].ID != "a" || pending[1].ID != "c" {
+		t.Errorf("Expected the jobs around the corrupt line, got %+v", pending)
+	}
+	quarantined, _ := os.ReadFile(path + ".corrupt")
+	if string(quarantined) != garbage+"\n" {
+		t.Errorf("Expected the corrupt line to be quarantined, got %q", quarantined)
+	}
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), garbage) {
+		t.Errorf("Expected the corrupt line to be removed from the log, got %q", data)
+	}
+}
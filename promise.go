@@ -0,0 +1,408 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Future represents the eventual result of an asynchronous operation.
+//
+// Callbacks registered with Then, Catch and Finally run on the
+// goroutine that settles the future, or immediately when it has already
+// settled, so chaining never starts goroutines.
+type Future[T any] struct {
+	mu        sync.Mutex
+	value     T
+	err       error
+	settled   bool
+	done      chan struct{}
+	callbacks []func()
+	cancel    func()
+}
+
+// Promise represents an asynchronous request result
+type Promise = Future[*Response]
+
+// NewPromise creates a new promise
+func NewPromise() *Promise {
+	return NewFuture[*Response]()
+}
+
+// NewFuture creates a new pending future
+func NewFuture[T any]() *Future[T] {
+	return &Future[T]{
+		done: make(chan struct{}),
+	}
+}
+
+// Resolved returns a future resolved with value
+func Resolved[T any](value T) *Future[T] {
+	p := NewFuture[T]()
+	p.Resolve(value)
+	return p
+}
+
+// Rejected returns a future rejected with err
+func Rejected[T any](err error) *Future[T] {
+	p := NewFuture[T]()
+	p.Reject(err)
+	return p
+}
+
+// newCancellableFuture creates a pending future whose Cancel calls cancel
+func newCancellableFuture[T any](cancel func()) *Future[T] {
+	p := NewFuture[T]()
+	p.cancel = cancel
+	return p
+}
+
+// Resolve resolves the future with a value
+func (p *Future[T]) Resolve(value T) {
+	p.settle(value, nil)
+}
+
+// Reject rejects the future with an error
+func (p *Future[T]) Reject(err error) {
+	var zero T
+	p.settle(zero, err)
+}
+
+// Wait waits for the future to be resolved or rejected
+func (p *Future[T]) Wait() (T, error) {
+	<-p.done
+	return p.value, p.err
+}
+
+// WaitContext waits for the future like Wait, or until ctx is done
+func (p *Future[T]) WaitContext(ctx context.Context) (T, error) {
+	select {
+	case <-p.done:
+		return p.value, p.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// Done returns a channel closed once the future has settled
+func (p *Future[T]) Done() <-chan struct{} {
+	return p.done
+}
+
+// Result returns the value and error of the future without blocking. The
+// second result reports whether the future has settled.
+func (p *Future[T]) Result() (T, bool, error) {
+	select {
+	case <-p.done:
+		return p.value, true, p.err
+	default:
+		var zero T
+		return zero, false, nil
+	}
+}
+
+// Cancel aborts the operation behind the future, such as the request of
+// SendAsync, and rejects the future with context.Canceled if it has not
+// settled yet
+func (p *Future[T]) Cancel() {
+	p.mu.Lock()
+	cancel := p.cancel
+	p.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	p.Reject(context.Canceled)
+}
+
+// Then returns a future settled like the future fn returns for the value
+// of p, such as another request; return Resolved or Rejected to continue
+// synchronously. Errors are passed through without calling fn. Cancelling
+// the returned future cancels p, then the future returned by fn.
+func (p *Future[T]) Then(fn func(T) *Future[T]) *Future[T] {
+	next := newCancellableFuture[T](p.Cancel)
+	p.onSettle(func() {
+		if p.err != nil {
+			next.Reject(p.err)
+			return
+		}
+		next.follow(fn(p.value))
+	})
+	return next
+}
+
+// Catch returns a future settled by fn when p is rejected: it recovers
+// when fn returns a nil error, and is rejected with the error fn returns
+// otherwise. Values are passed through without calling fn.
+func (p *Future[T]) Catch(fn func(error) (T, error)) *Future[T] {
+	next := newCancellableFuture[T](p.Cancel)
+	p.onSettle(func() {
+		if p.err == nil {
+			next.Resolve(p.value)
+			return
+		}
+		next.settle(fn(p.err))
+	})
+	return next
+}
+
+// Finally returns a future settled like p once fn has run
+func (p *Future[T]) Finally(fn func()) *Future[T] {
+	next := newCancellableFuture[T](p.Cancel)
+	p.onSettle(func() {
+		fn()
+		next.settle(p.value, p.err)
+	})
+	return next
+}
+
+// Map returns a future resolved with fn applied to the value of p,
+// possibly of another type. Errors are passed through without calling fn.
+func Map[T, U any](p *Future[T], fn func(T) (U, error)) *Future[U] {
+	next := newCancellableFuture[U](p.Cancel)
+	p.onSettle(func() {
+		if p.err != nil {
+			next.Reject(p.err)
+			return
+		}
+		next.settle(fn(p.value))
+	})
+	return next
+}
+
+// follow settles p like inner, which Cancel then cancels. A nil inner
+// resolves p with the zero value; inner is cancelled if p already settled.
+func (p *Future[T]) follow(inner *Future[T]) {
+	if inner == nil {
+		var zero T
+		p.Resolve(zero)
+		return
+	}
+
+	p.mu.Lock()
+	settled := p.settled
+	if !settled {
+		p.cancel = inner.Cancel
+	}
+	p.mu.Unlock()
+	if settled {
+		inner.Cancel()
+		return
+	}
+	inner.onSettle(func() {
+		p.settle(inner.value, inner.err)
+	})
+}
+
+// settle resolves or rejects the future once and runs its callbacks. It
+// reports whether this call settled the future.
+func (p *Future[T]) settle(value T, err error) bool {
+	p.mu.Lock()
+	if p.settled {
+		p.mu.Unlock()
+		return false
+	}
+	p.settled = true
+	p.value, p.err = value, err
+	callbacks := p.callbacks
+	p.callbacks = nil
+	p.cancel = nil
+	close(p.done)
+	p.mu.Unlock()
+
+	for _, callback := range callbacks {
+		callback()
+	}
+	return true
+}
+
+// onSettle runs fn once the future has settled
+func (p *Future[T]) onSettle(fn func()) {
+	p.mu.Lock()
+	if !p.settled {
+		p.callbacks = append(p.callbacks, fn)
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+	fn()
+}
+
+// Settled is the outcome of a future collected by AllSettled
+type Settled[T any] struct {
+	Value T
+	Err   error
+}
+
+// AggregateError is the rejection of Any when every future was rejected
+type AggregateError struct {
+	Errors []error
+}
+
+// Error implements error
+func (e *AggregateError) Error() string {
+	if len(e.Errors) == 0 {
+		return "httpclient: no futures to wait for"
+	}
+	return fmt.Sprintf("httpclient: all %d futures rejected, first: %v", len(e.Errors), e.Errors[0])
+}
+
+// Unwrap returns the rejections so errors.Is and errors.As can match them
+func (e *AggregateError) Unwrap() []error {
+	return e.Errors
+}
+
+// All returns a future resolved with the values of futures, in order,
+// once they have all resolved. It rejects with the first rejection, or
+// ctx.Err() when ctx is done first, and then cancels the other futures.
+func All[T any](ctx context.Context, futures ...*Future[T]) *Future[[]T] {
+	result := newCancellableFuture[[]T](func() { cancelAll(futures) })
+	fail := func(err error) {
+		if result.settle(nil, err) {
+			cancelAll(futures)
+		}
+	}
+
+	values := make([]T, len(futures))
+	var mu sync.Mutex
+	remaining := len(futures)
+	if remaining == 0 {
+		result.Resolve(values)
+		return result
+	}
+	watch(ctx, result, fail)
+
+	for i, p := range futures {
+		i, p := i, p
+		p.onSettle(func() {
+			if p.err != nil {
+				fail(p.err)
+				return
+			}
+			mu.Lock()
+			values[i] = p.value
+			remaining--
+			complete := remaining == 0
+			mu.Unlock()
+			if complete {
+				result.Resolve(values)
+			}
+		})
+	}
+	return result
+}
+
+// AllSettled returns a future resolved with the outcome of every future,
+// in order. When ctx is done the pending futures are cancelled, so their
+// outcome is context.Canceled.
+func AllSettled[T any](ctx context.Context, futures ...*Future[T]) *Future[[]Settled[T]] {
+	result := newCancellableFuture[[]Settled[T]](func() { cancelAll(futures) })
+
+	outcomes := make([]Settled[T], len(futures))
+	var mu sync.Mutex
+	remaining := len(futures)
+	if remaining == 0 {
+		result.Resolve(outcomes)
+		return result
+	}
+	watch(ctx, result, func(error) { cancelAll(futures) })
+
+	for i, p := range futures {
+		i, p := i, p
+		p.onSettle(func() {
+			mu.Lock()
+			outcomes[i] = Settled[T]{Value: p.value, Err: p.err}
+			remaining--
+			complete := remaining == 0
+			mu.Unlock()
+			if complete {
+				result.Resolve(outcomes)
+			}
+		})
+	}
+	return result
+}
+
+// Race returns a future settled like the first of futures to settle,
+// or rejected with ctx.Err() when ctx is done first. The other futures
+// are then cancelled. A race of no futures settles when ctx is done.
+func Race[T any](ctx context.Context, futures ...*Future[T]) *Future[T] {
+	result := newCancellableFuture[T](func() { cancelAll(futures) })
+	finish := func(value T, err error) {
+		if result.settle(value, err) {
+			cancelAll(futures)
+		}
+	}
+	watch(ctx, result, func(err error) {
+		var zero T
+		finish(zero, err)
+	})
+
+	for _, p := range futures {
+		p := p
+		p.onSettle(func() {
+			finish(p.value, p.err)
+		})
+	}
+	return result
+}
+
+// Any returns a future resolved with the first of futures to resolve,
+// then cancels the others. It rejects with an AggregateError when every
+// future was rejected, or with ctx.Err() when ctx is done first.
+func Any[T any](ctx context.Context, futures ...*Future[T]) *Future[T] {
+	result := newCancellableFuture[T](func() { cancelAll(futures) })
+	finish := func(value T, err error) {
+		if result.settle(value, err) {
+			cancelAll(futures)
+		}
+	}
+
+	errs := make([]error, len(futures))
+	var mu sync.Mutex
+	remaining := len(futures)
+	if remaining == 0 {
+		result.Reject(&AggregateError{})
+		return result
+	}
+	watch(ctx, result, func(err error) {
+		var zero T
+		finish(zero, err)
+	})
+
+	for i, p := range futures {
+		i, p := i, p
+		p.onSettle(func() {
+			if p.err == nil {
+				finish(p.value, nil)
+				return
+			}
+			mu.Lock()
+			errs[i] = p.err
+			remaining--
+			complete := remaining == 0
+			mu.Unlock()
+			if complete {
+				var zero T
+				finish(zero, &AggregateError{Errors: errs})
+			}
+		})
+	}
+	return result
+}
+
+// watch calls onDone with ctx.Err() when ctx is done before result settles
+func watch[T any](ctx context.Context, result *Future[T], onDone func(error)) {
+	stop := context.AfterFunc(ctx, func() {
+		onDone(ctx.Err())
+	})
+	result.onSettle(func() { stop() })
+}
+
+// cancelAll cancels every future
+func cancelAll[T any](futures []*Future[T]) {
+	for _, p := range futures {
+		p.Cancel()
+	}
+}
@@ -0,0 +1,327 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFuture_Chaining(t *testing.T) {
+	p := NewFuture[int]()
+	doubled := p.Then(func(v int) *Future[int] { return Resolved(v * 2) })
+	incremented := doubled.Then(func(v int) *Future[int] {
+		inner := NewFuture[int]()
+		go inner.Resolve(v + 1)
+		return inner
+	})
+	formatted := Map(incremented, func(v int) (string, error) { return strconv.Itoa(v), nil })
+
+	var order []string
+	final := formatted.Finally(func() { order = append(order, "finally") })
+
+	p.Resolve(20)
+	value, err := final.Wait()
+	if err != nil || value != "41" {
+		t.Errorf("Expected '41', got '%s' (%v)", value, err)
+	}
+	if len(order) != 1 {
+		t.Errorf("Expected Finally to run once, got %v", order)
+	}
+}
+
+func TestFuture_Catch(t *testing.T) {
+	failure := errors.New("boom")
+	p := NewFuture[string]()
+
+	var thenCalled bool
+	recovered := p.Then(func(v string) *Future[string] {
+		thenCalled = true
+		return Resolved(v)
+	}).Catch(func(err error) (string, error) {
+		if !errors.Is(err, failure) {
+			t.Errorf("Expected the rejection to reach Catch, got %v", err)
+		}
+		return "fallback", nil
+	})
+	p.Reject(failure)
+
+	value, err := recovered.Wait()
+	if err != nil || value != "fallback" {
+		t.Errorf("Expected recovery, got '%s' (%v)", value, err)
+	}
+	if thenCalled {
+		t.Error("Expected Then to be skipped on rejection")
+	}
+
+	source := NewFuture[string]()
+	wrapped := source.Catch(func(err error) (string, error) { return "", fmt.Errorf("fetching: %w", err) })
+	source.Reject(failure)
+	if _, err := wrapped.Wait(); !errors.Is(err, failure) || err.Error() != "fetching: boom" {
+		t.Errorf("Expected the error returned by Catch, got %v", err)
+	}
+}
+
+func TestFuture_CancelThen(t *testing.T) {
+	var cancelled atomic.Bool
+	p := NewFuture[int]()
+	chained := p.Then(func(v int) *Future[int] { return trackedFuture[int](&cancelled) })
+
+	p.Resolve(1)
+	chained.Cancel()
+
+	if _, err := chained.Wait(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if !cancelled.Load() {
+		t.Error("Expected the future returned by Then to be cancelled")
+	}
+}
+
+func TestFuture_WaitContextAndResult(t *testing.T) {
+	p := NewFuture[int]()
+
+	if _, ok, _ := p.Result(); ok {
+		t.Error("Expected a pending future")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := p.WaitContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+
+	p.Resolve(7)
+	select {
+	case <-p.Done():
+	default:
+		t.Error("Expected Done to be closed")
+	}
+	if value, ok, err := p.Result(); !ok || err != nil || value != 7 {
+		t.Errorf("Expected settled value 7, got %d (%v, %v)", value, err, ok)
+	}
+	p.Reject(errors.New("late"))
+	if value, err := p.Wait(); err != nil || value != 7 {
+		t.Errorf("Expected a future to settle once, got %d (%v)", value, err)
+	}
+}
+
+func TestFuture_NoGoroutinePerThen(t *testing.T) {
+	p := NewFuture[int]()
+	before := runtime.NumGoroutine()
+
+	chained := p
+	for i := 0; i < 1000; i++ {
+		chained = chained.Then(func(v int) *Future[int] { return Resolved(v + 1) })
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("Expected no goroutines for pending callbacks, got %d more", after-before)
+	}
+
+	p.Resolve(0)
+	if value, _ := chained.Wait(); value != 1000 {
+		t.Errorf("Expected 1000, got %d", value)
+	}
+}
+
+func TestPromise_ThenAndCatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(r.URL.Path))
+	}))
+	defer server.Close()
+
+	client := NewAsyncClient(WithBaseURL(server.URL))
+	posts := client.GetAsync("/users", nil).Then(func(resp *Response) *Promise {
+		return client.GetAsync(resp.GetBody()+"/posts", nil)
+	})
+	if resp, err := posts.Wait(); err != nil || resp.GetBody() != "/users/posts" {
+		t.Errorf("Expected the response of the second request, got %v", err)
+	}
+
+	fallback := &Response{Body: []byte("cached")}
+	recovered := client.GetAsync("/missing", nil).Then(func(resp *Response) *Promise {
+		if resp.GetStatusCode() != http.StatusOK {
+			return Rejected[*Response](&StatusError{Response: resp})
+		}
+		return Resolved(resp)
+	}).Catch(func(err error) (*Response, error) {
+		return fallback, nil
+	})
+	if resp, err := recovered.Wait(); err != nil || resp != fallback {
+		t.Errorf("Expected Catch to recover with the fallback, got %v (%v)", resp, err)
+	}
+}
+
+func TestAsyncClient_Cancel(t *testing.T) {
+	aborted := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			close(aborted)
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+
+	client := NewAsyncClient(WithBaseURL(server.URL))
+	promise := client.GetAsync("/slow", nil)
+	chained := promise.Then(func(resp *Response) *Promise { return Resolved(resp) })
+
+	time.Sleep(20 * time.Millisecond)
+	chained.Cancel()
+
+	if _, err := chained.Wait(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if _, err := promise.Wait(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the source promise to be cancelled, got %v", err)
+	}
+	select {
+	case <-aborted:
+	case <-time.After(time.Second):
+		t.Error("Expected the request to be aborted")
+	}
+}
+
+// trackedFuture returns a pending future recording whether it was cancelled
+func trackedFuture[T any](cancelled *atomic.Bool) *Future[T] {
+	return newCancellableFuture[T](func() { cancelled.Store(true) })
+}
+
+func TestAll(t *testing.T) {
+	a, b := NewFuture[int](), NewFuture[int]()
+	all := All(context.Background(), a, b)
+	b.Resolve(2)
+	a.Resolve(1)
+
+	values, err := all.Wait()
+	if err != nil || len(values) != 2 || values[0] != 1 || values[1] != 2 {
+		t.Errorf("Expected [1 2], got %v (%v)", values, err)
+	}
+
+	failure := errors.New("boom")
+	var cancelled atomic.Bool
+	failing, sibling := NewFuture[int](), trackedFuture[int](&cancelled)
+	all = All(context.Background(), failing, sibling)
+	failing.Reject(failure)
+
+	if _, err := all.Wait(); !errors.Is(err, failure) {
+		t.Errorf("Expected the first rejection, got %v", err)
+	}
+	if !cancelled.Load() {
+		t.Error("Expected the sibling to be cancelled")
+	}
+	if _, err := sibling.Wait(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the sibling to be rejected with context.Canceled, got %v", err)
+	}
+}
+
+func TestAll_Context(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+	client := NewAsyncClient(WithBaseURL(server.URL))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	first, second := client.GetAsync("/a", nil), client.GetAsync("/b", nil)
+	start := time.Now()
+
+	if _, err := All(ctx, first, second).Wait(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	if _, err := second.Wait(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the requests to be cancelled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected All to stop at the deadline, took %v", elapsed)
+	}
+}
+
+func TestAllSettled(t *testing.T) {
+	failure := errors.New("boom")
+	a, b, c := NewFuture[string](), NewFuture[string](), NewFuture[string]()
+	ctx, cancel := context.WithCancel(context.Background())
+	settled := AllSettled(ctx, a, b, c)
+
+	a.Resolve("ok")
+	b.Reject(failure)
+	cancel()
+
+	outcomes, err := settled.Wait()
+	if err != nil || len(outcomes) != 3 {
+		t.Fatalf("Expected 3 outcomes, got %v (%v)", outcomes, err)
+	}
+	if outcomes[0].Value != "ok" || outcomes[0].Err != nil {
+		t.Errorf("Expected first outcome 'ok', got %+v", outcomes[0])
+	}
+	if !errors.Is(outcomes[1].Err, failure) {
+		t.Errorf("Expected second outcome to fail, got %+v", outcomes[1])
+	}
+	if !errors.Is(outcomes[2].Err, context.Canceled) {
+		t.Errorf("Expected pending outcome to be cancelled, got %+v", outcomes[2])
+	}
+}
+
+func TestRace(t *testing.T) {
+	var cancelled atomic.Bool
+	slow, fast := trackedFuture[string](&cancelled), NewFuture[string]()
+	race := Race(context.Background(), slow, fast)
+
+	failure := errors.New("boom")
+	fast.Reject(failure)
+	if _, err := race.Wait(); !errors.Is(err, failure) {
+		t.Errorf("Expected the first settled result, got %v", err)
+	}
+	if !cancelled.Load() {
+		t.Error("Expected the loser to be cancelled")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Race[int](ctx).Wait(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected an empty race to end with its context, got %v", err)
+	}
+}
+
+func TestAny(t *testing.T) {
+	first, second := errors.New("first"), errors.New("second")
+	a, b, c := NewFuture[int](), NewFuture[int](), NewFuture[int]()
+	anyOf := Any(context.Background(), a, b, c)
+	a.Reject(first)
+	c.Resolve(3)
+
+	if value, err := anyOf.Wait(); err != nil || value != 3 {
+		t.Errorf("Expected the first success, got %d (%v)", value, err)
+	}
+	if _, err := b.Wait(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the others to be cancelled, got %v", err)
+	}
+
+	a, b = NewFuture[int](), NewFuture[int]()
+	anyOf = Any(context.Background(), a, b)
+	b.Reject(second)
+	a.Reject(first)
+
+	_, err := anyOf.Wait()
+	var aggregate *AggregateError
+	if !errors.As(err, &aggregate) || len(aggregate.Errors) != 2 {
+		t.Fatalf("Expected an AggregateError of 2 errors, got %v", err)
+	}
+	if aggregate.Errors[0] != first || !errors.Is(err, second) {
+		t.Errorf("Expected the errors in future order, got %v", aggregate.Errors)
+	}
+}
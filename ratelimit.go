@@ -0,0 +1,290 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned by RateLimitMiddleware in fail-fast mode when
+// a request would exceed a limit
+var ErrRateLimited = errors.New("httpclient: rate limited")
+
+// RateLimit is a token bucket refilled at Rate tokens per second holding
+// at most Burst tokens. A Rate of zero or less means no limit.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimitOptions configures RateLimitMiddleware
+type RateLimitOptions struct {
+	Global  *RateLimit           // shared by all requests
+	PerHost *RateLimit           // applied to each host without an override
+	Hosts   map[string]RateLimit // per host overrides, keyed by host[:port]
+	Routes  map[string]RateLimit // per route template
+
+	// FailFast returns ErrRateLimited instead of waiting for a token
+	FailFast bool
+}
+
+// RateLimitMiddleware limits the request rate globally, per host and per
+// route template. It also pauses a host when responses report an exhausted
+// quota through RateLimit-*/X-RateLimit-* headers or a 429 status.
+func RateLimitMiddleware(options *RateLimitOptions) Middleware {
+	if options == nil {
+		options = &RateLimitOptions{}
+	}
+	rl := &rateLimiter{
+		options: options,
+		hosts:   make(map[string]*tokenBucket),
+		routes:  make(map[string]*tokenBucket),
+		paused:  make(map[string]time.Time),
+	}
+	if options.Global != nil {
+		rl.global = newTokenBucket(*options.Global)
+	}
+
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*Response, error) {
+			if err := rl.wait(req); err != nil {
+				return nil, err
+			}
+
+			resp, err := next(req)
+			if err == nil {
+				rl.observe(req.URL.Host, resp)
+			}
+			return resp, err
+		}
+	}
+}
+
+// rateLimiter holds the buckets of a RateLimitMiddleware
+type rateLimiter struct {
+	mu      sync.Mutex
+	options *RateLimitOptions
+	global  *tokenBucket
+	hosts   map[string]*tokenBucket
+	routes  map[string]*tokenBucket
+	paused  map[string]time.Time
+}
+
+// buckets returns the buckets that apply to req and the time until
+// which its host is paused
+func (rl *rateLimiter) buckets(req *http.Request) ([]*tokenBucket, time.Time) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	var buckets []*tokenBucket
+	if rl.global != nil {
+		buckets = append(buckets, rl.global)
+	}
+
+	host := req.URL.Host
+	if bucket, ok := rl.hosts[host]; ok {
+		buckets = append(buckets, bucket)
+	} else if limit, ok := rl.options.Hosts[host]; ok {
+		rl.hosts[host] = newTokenBucket(limit)
+		buckets = append(buckets, rl.hosts[host])
+	} else if rl.options.PerHost != nil {
+		rl.hosts[host] = newTokenBucket(*rl.options.PerHost)
+		buckets = append(buckets, rl.hosts[host])
+	}
+
+	route := routeOf(req)
+	if bucket, ok := rl.routes[route]; ok {
+		buckets = append(buckets, bucket)
+	} else if limit, ok := rl.options.Routes[route]; ok {
+		rl.routes[route] = newTokenBucket(limit)
+		buckets = append(buckets, rl.routes[route])
+	}
+
+	return buckets, rl.paused[host]
+}
+
+// wait blocks until req may be sent, or fails in fail-fast mode
+func (rl *rateLimiter) wait(req *http.Request) error {
+	buckets, pausedUntil := rl.buckets(req)
+	now := time.Now()
+
+	if rl.options.FailFast {
+		if now.Before(pausedUntil) {
+			return fmt.Errorf("%w: %s paused for %v", ErrRateLimited, req.URL.Host, pausedUntil.Sub(now))
+		}
+		for i, bucket := range buckets {
+			if !bucket.take(now) {
+				for _, taken := range buckets[:i] {
+					taken.cancel()
+				}
+				return fmt.Errorf("%w: %s %s", ErrRateLimited, req.Method, req.URL.String())
+			}
+		}
+		return nil
+	}
+
+	delay := pausedUntil.Sub(now)
+	for _, bucket := range buckets {
+		if d := bucket.reserve(now); d > delay {
+			delay = d
+		}
+	}
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		for _, bucket := range buckets {
+			bucket.cancel()
+		}
+		return req.Context().Err()
+	}
+}
+
+// observe adapts to the quota reported by a response
+func (rl *rateLimiter) observe(host string, resp *Response) {
+	now := time.Now()
+	var until time.Time
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		until = now.Add(time.Second)
+		if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now); ok {
+			until = now.Add(retryAfter)
+		}
+	} else if remaining, ok := rateLimitHeader(resp.Header, "Remaining"); ok && remaining == 0 {
+		if reset, ok := rateLimitHeader(resp.Header, "Reset"); ok {
+			until = rateLimitReset(reset, now)
+		}
+	}
+
+	if until.IsZero() {
+		return
+	}
+
+	rl.mu.Lock()
+	if until.After(rl.paused[host]) {
+		rl.paused[host] = until
+	}
+	rl.mu.Unlock()
+}
+
+// rateLimitHeader reads RateLimit-<name> or X-RateLimit-<name>
+func rateLimitHeader(header http.Header, name string) (int64, bool) {
+	value := header.Get("RateLimit-" + name)
+	if value == "" {
+		value = header.Get("X-RateLimit-" + name)
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	return n, err == nil
+}
+
+// rateLimitReset interprets a reset value as delta seconds, or as a Unix
+// timestamp when it is too large to be a delta
+func rateLimitReset(reset int64, now time.Time) time.Time {
+	if reset > 1e9 {
+		return time.Unix(reset, 0)
+	}
+	return now.Add(time.Duration(reset) * time.Second)
+}
+
+// parseRetryAfter parses a Retry-After header in seconds or HTTP-date form
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return date.Sub(now), true
+	}
+	return 0, false
+}
+
+// tokenBucket is a token bucket allowing reservations into debt
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket creates a full bucket
+func newTokenBucket(limit RateLimit) *tokenBucket {
+	burst := float64(limit.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   limit.Rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// refill adds the tokens accumulated since the last update
+func (b *tokenBucket) refill(now time.Time) {
+	if now.After(b.last) {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+}
+
+// take consumes a token if one is available
+func (b *tokenBucket) take(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.rate <= 0 {
+		return true
+	}
+	b.refill(now)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// reserve consumes a token and returns how long to wait before using it
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.rate <= 0 {
+		return 0
+	}
+	b.refill(now)
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel returns a token taken by take or reserve
+func (b *tokenBucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.rate <= 0 {
+		return
+	}
+	b.tokens++
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
@@ -0,0 +1,139 @@
+package httpclient
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimitMiddleware_Wait(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(
+		WithBaseURL(server.URL),
+		WithMiddleware(RateLimitMiddleware(&RateLimitOptions{
+			PerHost: &RateLimit{Rate: 20, Burst: 1},
+		})),
+	)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := client.Get("/test", nil); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("Expected requests to be spaced by the rate limit, took %v", elapsed)
+	}
+}
+
+func TestRateLimitMiddleware_FailFast(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(
+		WithBaseURL(server.URL),
+		WithMiddleware(RateLimitMiddleware(&RateLimitOptions{
+			Routes:   map[string]RateLimit{"/search": {Rate: 1, Burst: 2}},
+			FailFast: true,
+		})),
+	)
+
+	search := &RequestOptions{Route: "/search"}
+	client.Get("/search?q=a", search)
+	client.Get("/search?q=b", search)
+
+	if _, err := client.Get("/search?q=c", search); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected ErrRateLimited, got %v", err)
+	}
+
+	if _, err := client.Get("/other", nil); err != nil {
+		t.Errorf("Expected other routes not to be limited, got %v", err)
+	}
+}
+
+func TestRateLimitMiddleware_ZeroRate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	for _, failFast := range []bool{false, true} {
+		client := NewClient(
+			WithBaseURL(server.URL),
+			WithMiddleware(RateLimitMiddleware(&RateLimitOptions{
+				PerHost:  &RateLimit{Rate: 0, Burst: 1},
+				FailFast: failFast,
+			})),
+		)
+
+		start := time.Now()
+		for i := 0; i < 5; i++ {
+			if _, err := client.Get("/test", nil); err != nil {
+				t.Fatalf("Expected a zero rate not to limit with FailFast %v, got %v", failFast, err)
+			}
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Expected a zero rate not to delay with FailFast %v, took %v", failFast, elapsed)
+		}
+	}
+}
+
+func TestRateLimitMiddleware_Adaptive(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(
+		WithBaseURL(server.URL),
+		WithMiddleware(RateLimitMiddleware(&RateLimitOptions{FailFast: true})),
+	)
+
+	resp, err := client.Get("/test", nil)
+	if err != nil || resp.GetStatusCode() != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 response, got %v", err)
+	}
+
+	if _, err := client.Get("/test", nil); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected host to be paused after 429, got %v", err)
+	}
+
+	if calls != 1 {
+		t.Errorf("Expected 1 upstream call, got %d", calls)
+	}
+}
+
+func TestRateLimitMiddleware_RemainingHeader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", "30")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(
+		WithBaseURL(server.URL),
+		WithMiddleware(RateLimitMiddleware(&RateLimitOptions{FailFast: true})),
+	)
+
+	client.Get("/test", nil)
+	if _, err := client.Get("/test", nil); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected host to be paused after quota exhaustion, got %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"time"
+)
+
+// DefaultRequestIDHeader is the header carrying the request ID
+const DefaultRequestIDHeader = "X-Request-ID"
+
+// WithRequestIDHeader sets the header carrying the request ID. An empty
+// name disables request IDs.
+func WithRequestIDHeader(name string) ClientOption {
+	return func(c *Client) {
+		c.requestIDHeader = name
+	}
+}
+
+// WithRequestID returns a context carrying the request ID to send
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx, if any
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+// NewRequestID generates a time-ordered UUIDv7
+func NewRequestID() string {
+	var uuid [16]byte
+	binary.BigEndian.PutUint64(uuid[:8], uint64(time.Now().UnixMilli())<<16)
+	rand.Read(uuid[6:])
+	uuid[6] = uuid[6]&0x0f | 0x70 // version 7
+	uuid[8] = uuid[8]&0x3f | 0x80 // RFC 4122 variant
+
+	var buf [36]byte
+	hex.Encode(buf[0:8], uuid[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], uuid[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], uuid[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], uuid[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], uuid[10:])
+	return string(buf[:])
+}
+
+// GetRequestID returns the ID the request was sent with
+func (r *Response) GetRequestID() string {
+	return r.RequestID
+}
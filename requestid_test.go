@@ -0,0 +1,77 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+type recordingLogger struct {
+	lines []string
+}
+
+func (rl *recordingLogger) Logf(format string, args ...interface{}) {
+	rl.lines = append(rl.lines, fmt.Sprintf(format, args...))
+}
+
+func TestClient_RequestID(t *testing.T) {
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get("X-Request-ID")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	logger := &recordingLogger{}
+	client := NewClient(WithBaseURL(server.URL), WithMiddleware(LoggingMiddleware(logger)))
+
+	resp, err := client.Get("/test", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	uuidv7 := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if !uuidv7.MatchString(received) {
+		t.Errorf("Expected a generated UUIDv7 request ID, got '%s'", received)
+	}
+
+	if resp.GetRequestID() != received {
+		t.Errorf("Expected response request ID '%s', got '%s'", received, resp.GetRequestID())
+	}
+
+	if !strings.Contains(logger.lines[0], received) {
+		t.Errorf("Expected log line to contain the request ID, got '%s'", logger.lines[0])
+	}
+
+	ctx := WithRequestID(context.Background(), "req-123")
+	resp, err = client.RequestWithContext(ctx, "GET", "/test", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if received != "req-123" || resp.GetRequestID() != "req-123" {
+		t.Errorf("Expected request ID from context, got '%s' and '%s'", received, resp.GetRequestID())
+	}
+}
+
+func TestClient_RequestIDHeader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Correlation-ID") == "" {
+			t.Error("Expected X-Correlation-ID header to be set")
+		}
+		if r.Header.Get("X-Request-ID") != "" {
+			t.Error("Expected X-Request-ID header not to be set")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewClient(WithBaseURL(server.URL), WithRequestIDHeader("X-Correlation-ID"))
+	if _, err := client.Get("/test", nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+}
@@ -0,0 +1,270 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrScheduledJobNotFound is returned for an unknown scheduled job name
+	ErrScheduledJobNotFound = errors.New("httpclient: scheduled job not found")
+	// ErrDuplicateJob is returned when adding a job under a name in use
+	ErrDuplicateJob = errors.New("httpclient: duplicate scheduled job")
+	// ErrSchedulerRunning is returned by Run when the scheduler already runs
+	ErrSchedulerRunning = errors.New("httpclient: scheduler already running")
+)
+
+// ScheduledJob is a request sent by a Scheduler on a Schedule
+type ScheduledJob struct {
+	// Name identifies the job in the Scheduler
+	Name string
+	// Request is sent on every run, so its options should not carry a
+	// Body reader, which can only be read once
+	Request  ConcurrentRequest
+	Schedule Schedule
+	// Jitter delays each run by a random duration up to Jitter
+	Jitter time.Duration
+	// AllowOverlap starts a run even when the previous one is still in
+	// flight; by default such runs are skipped
+	AllowOverlap bool
+	// OnResponse is called with the outcome of every run
+	OnResponse func(*Response, error)
+	// OnChange is called with the response of a run whose body or ETag
+	// differs from the previous successful run, including the first one
+	OnChange func(*Response)
+}
+
+// Scheduler sends requests on fixed intervals or cron schedules.
+//
+// Once the ETag of a job is known, its runs are sent with If-None-Match so
+// that the server can answer 304 Not Modified when nothing changed.
+type Scheduler struct {
+	client *Client
+
+	mu      sync.Mutex
+	jobs    map[string]*scheduledEntry
+	ctx     context.Context
+	running sync.WaitGroup
+}
+
+// scheduledEntry is the state of a job in a Scheduler
+type scheduledEntry struct {
+	job      ScheduledJob
+	stop     context.CancelFunc
+	paused   bool
+	inFlight int
+
+	// Change detection, guarded by changeMu so that overlapping runs
+	// report each change once
+	changeMu sync.Mutex
+	etag     string
+	digest   [sha256.Size]byte
+	seen     bool
+}
+
+// NewScheduler creates a new scheduler sending requests through client
+func NewScheduler(client *Client) *Scheduler {
+	return &Scheduler{client: client, jobs: make(map[string]*scheduledEntry)}
+}
+
+// Add registers a job. It starts right away when the scheduler is running.
+func (s *Scheduler) Add(job ScheduledJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[job.Name]; ok {
+		return ErrDuplicateJob
+	}
+	entry := &scheduledEntry{job: job}
+	s.jobs[job.Name] = entry
+	if s.ctx != nil {
+		s.start(entry)
+	}
+	return nil
+}
+
+// Remove stops and unregisters a job; a run in flight is cancelled and
+// its outcome is not reported
+func (s *Scheduler) Remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.jobs[name]
+	if !ok {
+		return ErrScheduledJobNotFound
+	}
+	if entry.stop != nil {
+		entry.stop()
+	}
+	delete(s.jobs, name)
+	return nil
+}
+
+// Pause skips the runs of a job until it is resumed
+func (s *Scheduler) Pause(name string) error {
+	return s.setPaused(name, true)
+}
+
+// Resume resumes a paused job from its next scheduled run
+func (s *Scheduler) Resume(name string) error {
+	return s.setPaused(name, false)
+}
+
+// setPaused pauses or resumes a job
+func (s *Scheduler) setPaused(name string, paused bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.jobs[name]
+	if !ok {
+		return ErrScheduledJobNotFound
+	}
+	entry.paused = paused
+	return nil
+}
+
+// Run runs the jobs until ctx is done, then waits for the runs in flight,
+// which are cancelled with ctx, and returns the context error
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.mu.Lock()
+	if s.ctx != nil {
+		s.mu.Unlock()
+		return ErrSchedulerRunning
+	}
+	s.ctx = ctx
+	for _, entry := range s.jobs {
+		s.start(entry)
+	}
+	s.mu.Unlock()
+
+	<-ctx.Done()
+	// Clear the context first so that Add no longer starts loops
+	// while the running ones are awaited
+	s.mu.Lock()
+	s.ctx = nil
+	s.mu.Unlock()
+
+	s.running.Wait()
+	return ctx.Err()
+}
+
+// start starts the loop of a job; s.mu must be held
+func (s *Scheduler) start(entry *scheduledEntry) {
+	ctx, stop := context.WithCancel(s.ctx)
+	entry.stop = stop
+	s.running.Add(1)
+	go func() {
+		defer s.running.Done()
+		defer stop()
+		s.loop(ctx, entry)
+	}()
+}
+
+// loop waits for every run of a job and starts it
+func (s *Scheduler) loop(ctx context.Context, entry *scheduledEntry) {
+	job := entry.job
+	var runs sync.WaitGroup
+	defer runs.Wait()
+
+	for {
+		next := job.Schedule.Next(time.Now())
+		if next.IsZero() {
+			return
+		}
+		if job.Jitter > 0 {
+			next = next.Add(time.Duration(rand.Int63n(int64(job.Jitter))))
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+
+		s.mu.Lock()
+		skip := entry.paused || (entry.inFlight > 0 && !job.AllowOverlap)
+		if !skip {
+			entry.inFlight++
+		}
+		s.mu.Unlock()
+		if skip {
+			continue
+		}
+
+		runs.Add(1)
+		go func() {
+			defer runs.Done()
+			s.run(ctx, entry)
+
+			s.mu.Lock()
+			entry.inFlight--
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// run sends a single request of a job and reports its outcome
+func (s *Scheduler) run(ctx context.Context, entry *scheduledEntry) {
+	job := entry.job
+	options := &RequestOptions{}
+	if job.Request.Options != nil {
+		copied := *job.Request.Options
+		options = &copied
+	}
+
+	entry.changeMu.Lock()
+	etag := entry.etag
+	entry.changeMu.Unlock()
+	if etag != "" && options.Headers["If-None-Match"] == "" {
+		headers := make(map[string]string, len(options.Headers)+1)
+		for k, v := range options.Headers {
+			headers[k] = v
+		}
+		headers["If-None-Match"] = etag
+		options.Headers = headers
+	}
+
+	resp, err := s.client.RequestWithContext(ctx, job.Request.Method, job.Request.Path, options)
+	if ctx.Err() != nil {
+		return // removed or stopped while in flight
+	}
+	if job.OnResponse != nil {
+		job.OnResponse(resp, err)
+	}
+	if err == nil && job.OnChange != nil && entry.changed(resp) {
+		job.OnChange(resp)
+	}
+}
+
+// changed records a response and reports whether its body or ETag
+// differs from the previous successful response
+func (e *scheduledEntry) changed(resp *Response) bool {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return false // includes 304 Not Modified
+	}
+
+	e.changeMu.Lock()
+	defer e.changeMu.Unlock()
+
+	etag := resp.Header.Get("ETag")
+	digest := sha256.Sum256(resp.Body)
+	changed := !e.seen
+	if e.seen {
+		if etag != "" && e.etag != "" {
+			changed = etag != e.etag
+		} else {
+			changed = digest != e.digest
+		}
+	}
+
+	e.seen = true
+	e.etag = etag
+	e.digest = digest
+	return changed
+}
@@ -0,0 +1,171 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScheduler_ChangeDetection(t *testing.T) {
+	var calls, notModified int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The status changes on the fourth call
+		version := `"v1"`
+		if atomic.AddInt32(&calls, 1) >= 4 {
+			version = `"v2"`
+		}
+		w.Header().Set("ETag", version)
+		if r.Header.Get("If-None-Match") == version {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte(version))
+	}))
+	defer server.Close()
+
+	var mu sync.Mutex
+	var changes []string
+	scheduler := NewScheduler(NewClient(WithBaseURL(server.URL)))
+	err := scheduler.Add(ScheduledJob{
+		Name:     "status",
+		Request:  ConcurrentRequest{Method: http.MethodGet, Path: "/status"},
+		Schedule: Every(10 * time.Millisecond),
+		OnChange: func(resp *Response) {
+			mu.Lock()
+			changes = append(changes, resp.GetBody())
+			mu.Unlock()
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := scheduler.Add(ScheduledJob{Name: "status"}); !errors.Is(err, ErrDuplicateJob) {
+		t.Errorf("Expected ErrDuplicateJob, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- scheduler.Run(ctx) }()
+
+	for atomic.LoadInt32(&calls) < 6 {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context canceled, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(changes) != 2 || changes[0] != `"v1"` || changes[1] != `"v2"` {
+		t.Errorf("Expected changes v1 then v2, got %q", changes)
+	}
+	if atomic.LoadInt32(&notModified) < 3 {
+		t.Errorf("Expected unchanged polls to be answered with 304, got %d", notModified)
+	}
+}
+
+func TestScheduler_OverlapAndPause(t *testing.T) {
+	var running, peak, calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&running, 1)
+		if n > atomic.LoadInt32(&peak) {
+			atomic.StoreInt32(&peak, n)
+		}
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+	}))
+	defer server.Close()
+
+	scheduler := NewScheduler(NewClient(WithBaseURL(server.URL)))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- scheduler.Run(ctx) }()
+
+	// Jobs added while running start right away
+	time.Sleep(5 * time.Millisecond)
+	scheduler.Add(ScheduledJob{
+		Name:     "slow",
+		Request:  ConcurrentRequest{Method: http.MethodGet, Path: "/slow"},
+		Schedule: Every(5 * time.Millisecond),
+		Jitter:   time.Millisecond,
+	})
+
+	time.Sleep(200 * time.Millisecond)
+	if peak := atomic.LoadInt32(&peak); peak != 1 {
+		t.Errorf("Expected overlapping runs to be skipped, got %d in flight", peak)
+	}
+
+	if err := scheduler.Pause("slow"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	time.Sleep(60 * time.Millisecond) // let the run in flight finish
+	paused := atomic.LoadInt32(&calls)
+	time.Sleep(100 * time.Millisecond)
+	if calls := atomic.LoadInt32(&calls); calls != paused {
+		t.Errorf("Expected no runs while paused, got %d more", calls-paused)
+	}
+
+	scheduler.Resume("slow")
+	time.Sleep(100 * time.Millisecond)
+	if atomic.LoadInt32(&calls) == paused {
+		t.Errorf("Expected runs to resume")
+	}
+
+	if err := scheduler.Pause("missing"); !errors.Is(err, ErrScheduledJobNotFound) {
+		t.Errorf("Expected ErrScheduledJobNotFound, got %v", err)
+	}
+	if err := scheduler.Run(ctx); !errors.Is(err, ErrSchedulerRunning) {
+		t.Errorf("Expected ErrSchedulerRunning, got %v", err)
+	}
+
+	cancel()
+	<-done
+	if err := scheduler.Remove("slow"); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+func TestScheduler_RemoveInFlight(t *testing.T) {
+	entered := make(chan struct{}, 1)
+	release := make(chan struct{})
+	// Answer only once released, even if the request was cancelled
+	client := NewClient(WithMiddleware(func(next Handler) Handler {
+		return func(req *http.Request) (*Response, error) {
+			entered <- struct{}{}
+			<-release
+			return &Response{Response: &http.Response{StatusCode: http.StatusOK}}, nil
+		}
+	}))
+
+	var reported atomic.Bool
+	scheduler := NewScheduler(client)
+	scheduler.Add(ScheduledJob{
+		Name:       "sync",
+		Request:    ConcurrentRequest{Method: http.MethodGet, Path: "http://example.com/sync"},
+		Schedule:   Every(time.Millisecond),
+		OnResponse: func(*Response, error) { reported.Store(true) },
+		OnChange:   func(*Response) { reported.Store(true) },
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- scheduler.Run(ctx) }()
+
+	<-entered
+	scheduler.Remove("sync")
+	close(release)
+	cancel()
+	<-done
+
+	if reported.Load() {
+		t.Error("Expected a removed job not to report its run in flight")
+	}
+}
@@ -0,0 +1,121 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SRVRecord is a DNS SRV record
+type SRVRecord struct {
+	Target   string
+	Port     uint16
+	Priority uint16
+	Weight   uint16
+	TTL      time.Duration
+}
+
+// SRVResolver looks up the SRV records of _service._proto.name
+type SRVResolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) ([]SRVRecord, error)
+}
+
+// NetSRVResolver resolves SRV records with a net.Resolver. The standard
+// library does not expose record TTLs, so every record is given TTL.
+type NetSRVResolver struct {
+	Resolver *net.Resolver // defaults to net.DefaultResolver
+	TTL      time.Duration // defaults to 30s
+}
+
+// LookupSRV implements SRVResolver
+func (r *NetSRVResolver) LookupSRV(ctx context.Context, service, proto, name string) ([]SRVRecord, error) {
+	resolver := r.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	ttl := r.TTL
+	if ttl <= 0 {
+		ttl = 30 * time.Second
+	}
+
+	_, addrs, err := resolver.LookupSRV(ctx, service, proto, name)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]SRVRecord, len(addrs))
+	for i, addr := range addrs {
+		records[i] = SRVRecord{
+			Target:   addr.Target,
+			Port:     addr.Port,
+			Priority: addr.Priority,
+			Weight:   addr.Weight,
+			TTL:      ttl,
+		}
+	}
+	return records, nil
+}
+
+// SRVOptions configures WithSRVDiscovery
+type SRVOptions struct {
+	Resolver SRVResolver // defaults to a NetSRVResolver
+	Scheme   string      // defaults to "https" for the "https" service and "http" otherwise
+	Path     string      // base path prepended to every request path
+}
+
+// WithSRVDiscovery routes requests to the targets of the SRV records of
+// _service._proto.name. Targets with the lowest priority are used first and
+// balanced by weight; the records are looked up again when their TTL expires.
+func WithSRVDiscovery(service, proto, name string, options *SRVOptions) ClientOption {
+	if options == nil {
+		options = &SRVOptions{}
+	}
+	resolver := options.Resolver
+	if resolver == nil {
+		resolver = &NetSRVResolver{}
+	}
+	scheme := options.Scheme
+	if scheme == "" {
+		scheme = "http"
+		if service == "https" {
+			scheme = "https"
+		}
+	}
+	path := strings.TrimRight(options.Path, "/")
+
+	return func(c *Client) {
+		c.balancing.strategy = Weighted
+		c.balancing.source = func(ctx context.Context) ([]Endpoint, time.Duration, error) {
+			records, err := resolver.LookupSRV(ctx, service, proto, name)
+			if err != nil {
+				return nil, 0, fmt.Errorf("httpclient: SRV lookup of _%s._%s.%s: %w", service, proto, name, err)
+			}
+
+			var ttl time.Duration
+			endpoints := make([]Endpoint, 0, len(records))
+			for _, record := range records {
+				target := strings.TrimSuffix(record.Target, ".")
+				if target == "" {
+					continue // "." means the service is not available
+				}
+				if ttl == 0 || record.TTL < ttl {
+					ttl = record.TTL
+				}
+				// A weight of 0 still gets a small share of the requests
+				weight := int(record.Weight) * 100
+				if weight == 0 {
+					weight = 1
+				}
+				endpoints = append(endpoints, Endpoint{
+					URL:      scheme + "://" + net.JoinHostPort(target, strconv.Itoa(int(record.Port))) + path,
+					Weight:   weight,
+					Priority: int(record.Priority),
+				})
+			}
+			return endpoints, ttl, nil
+		}
+	}
+}
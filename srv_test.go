@@ -0,0 +1,108 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeSRVResolver struct {
+	mu      sync.Mutex
+	records []SRVRecord
+	err     error
+	lookups int
+}
+
+func (r *fakeSRVResolver) LookupSRV(ctx context.Context, service, proto, name string) ([]SRVRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lookups++
+	return r.records, r.err
+}
+
+func (r *fakeSRVResolver) set(records []SRVRecord) {
+	r.mu.Lock()
+	r.records = records
+	r.mu.Unlock()
+}
+
+func srvRecord(t *testing.T, server *httptest.Server, priority, weight uint16, ttl time.Duration) SRVRecord {
+	port, err := strconv.Atoi(mustParseURL(t, server.URL).Port())
+	if err != nil {
+		t.Fatalf("Failed to parse port: %v", err)
+	}
+	return SRVRecord{Target: "127.0.0.1.", Port: uint16(port), Priority: priority, Weight: weight, TTL: ttl}
+}
+
+func TestClient_SRVDiscovery(t *testing.T) {
+	var callsA, callsB int32
+	serverA := newCountingServer(&callsA, http.StatusOK)
+	defer serverA.Close()
+	serverB := newCountingServer(&callsB, http.StatusOK)
+	defer serverB.Close()
+
+	resolver := &fakeSRVResolver{records: []SRVRecord{
+		srvRecord(t, serverA, 10, 1, time.Hour),
+		srvRecord(t, serverB, 20, 1, time.Hour),
+	}}
+	client := NewClient(WithSRVDiscovery("api", "tcp", "example.internal", &SRVOptions{
+		Resolver: resolver,
+		Path:     "/v1",
+	}))
+
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get("/users", nil)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if resp.GetBody() != "/v1/users" {
+			t.Errorf("Expected path '/v1/users', got '%s'", resp.GetBody())
+		}
+	}
+
+	if callsA != 3 || callsB != 0 {
+		t.Errorf("Expected only the lowest priority target to be used, got %d and %d", callsA, callsB)
+	}
+	if resolver.lookups != 1 {
+		t.Errorf("Expected 1 lookup within the TTL, got %d", resolver.lookups)
+	}
+}
+
+func TestClient_SRVDiscoveryRefresh(t *testing.T) {
+	var callsA, callsB int32
+	serverA := newCountingServer(&callsA, http.StatusOK)
+	defer serverA.Close()
+	serverB := newCountingServer(&callsB, http.StatusOK)
+	defer serverB.Close()
+
+	resolver := &fakeSRVResolver{records: []SRVRecord{srvRecord(t, serverA, 0, 0, 20*time.Millisecond)}}
+	client := NewClient(WithSRVDiscovery("api", "tcp", "example.internal", &SRVOptions{Resolver: resolver}))
+
+	client.Get("/users", nil)
+	resolver.set([]SRVRecord{srvRecord(t, serverB, 0, 0, time.Hour)})
+
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&callsB) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+		client.Get("/users", nil)
+	}
+
+	if atomic.LoadInt32(&callsB) == 0 {
+		t.Error("Expected requests to move to the new target after the TTL expired")
+	}
+}
+
+func TestClient_SRVDiscoveryError(t *testing.T) {
+	resolver := &fakeSRVResolver{err: errors.New("no such host")}
+	client := NewClient(WithSRVDiscovery("api", "tcp", "example.internal", &SRVOptions{Resolver: resolver}))
+
+	if _, err := client.Get("/users", nil); err == nil || err.Error() != "httpclient: SRV lookup of _api._tcp.example.internal: no such host" {
+		t.Errorf("Expected lookup error, got %v", err)
+	}
+}
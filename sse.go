@@ -0,0 +1,306 @@
+package httpclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event is a Server-Sent Event
+type Event struct {
+	// ID is the last event ID, which Subscribe sends back as Last-Event-ID
+	// when it reconnects
+	ID string
+	// Event is the event type, "message" unless the server sets one
+	Event string
+	Data  string
+}
+
+// EventSourceOptions configures Subscribe
+type EventSourceOptions struct {
+	// Method defaults to GET
+	Method string
+	// Request holds the options of every connection
+	Request *RequestOptions
+	// LastEventID resumes the stream after the event with this ID
+	LastEventID string
+	// Retry is the reconnection delay until the server sets one, defaults
+	// to 3s. It doubles after every failed connection up to MaxRetry.
+	Retry time.Duration
+	// MaxRetry caps the reconnection delay, defaults to 1m
+	MaxRetry time.Duration
+	// OnError is called with every failed connection, and with
+	// io.ErrUnexpectedEOF when the server closes one
+	OnError func(error)
+}
+
+// maxEventLine is the longest line accepted in an event stream
+const maxEventLine = 1 << 20
+
+// Subscribe connects to a text/event-stream endpoint and delivers its
+// events on a channel. Connections go through the middleware, headers and
+// auth of the client like any other request; the client timeout does not
+// apply to the stream.
+//
+// Dropped connections are re-established after the retry interval, sent
+// by the server or set in options, with the ID of the last event in the
+// Last-Event-ID header. A 204 No Content response ends the stream. The
+// channel is closed once ctx is done or the stream ends.
+func (c *Client) Subscribe(ctx context.Context, path string, options *EventSourceOptions) <-chan Event {
+	if options == nil {
+		options = &EventSourceOptions{}
+	}
+	events := make(chan Event)
+
+	go func() {
+		defer close(events)
+
+		s := &eventStream{
+			events:      events,
+			lastEventID: options.LastEventID,
+			retry:       options.Retry,
+		}
+		if s.retry <= 0 {
+			s.retry = 3 * time.Second
+		}
+		maxRetry := options.MaxRetry
+		if maxRetry <= 0 {
+			maxRetry = time.Minute
+		}
+
+		failures := 0
+		for {
+			received, err := c.connectEventStream(ctx, path, options, s)
+			if ctx.Err() != nil {
+				return
+			}
+			if err == errStreamEnded {
+				return
+			}
+			if options.OnError != nil {
+				options.OnError(err)
+			}
+
+			if received {
+				failures = 0
+			}
+			delay := s.retry
+			for i := 0; i < failures && delay < maxRetry; i++ {
+				delay *= 2
+			}
+			if delay > maxRetry {
+				delay = maxRetry
+			}
+			failures++
+
+			if sleepContext(ctx, delay) != nil {
+				return
+			}
+		}
+	}()
+
+	return events
+}
+
+// errStreamEnded is returned when the server ends a stream for good
+var errStreamEnded = errors.New("httpclient: event stream ended")
+
+// connectEventStream opens a connection and reads its events until it
+// drops. It reports whether any event was received and the reason the
+// connection ended.
+func (c *Client) connectEventStream(ctx context.Context, path string, options *EventSourceOptions, s *eventStream) (bool, error) {
+	request := &RequestOptions{}
+	if options.Request != nil {
+		copied := *options.Request
+		request = &copied
+	}
+	headers := make(map[string]string, len(request.Headers)+3)
+	for k, v := range request.Headers {
+		headers[k] = v
+	}
+	headers["Accept"] = "text/event-stream"
+	headers["Cache-Control"] = "no-cache"
+	if s.lastEventID != "" {
+		headers["Last-Event-ID"] = s.lastEventID
+	}
+	request.Headers = headers
+
+	method := options.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	ctx, body := withStream(ctx)
+
+	resp, err := c.RequestWithContext(ctx, method, path, request)
+	if err != nil {
+		return false, err
+	}
+	stream := body.take()
+	if stream == nil {
+		return false, &StatusError{Response: resp}
+	}
+	defer stream.Close()
+
+	if resp.StatusCode == http.StatusNoContent {
+		return false, errStreamEnded
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/event-stream" {
+		return false, fmt.Errorf("httpclient: unexpected event stream content type %q", resp.Header.Get("Content-Type"))
+	}
+
+	return s.read(ctx, stream)
+}
+
+// eventStream holds the state kept across the connections of Subscribe
+type eventStream struct {
+	events      chan<- Event
+	lastEventID string
+	retry       time.Duration
+}
+
+// read parses events from r and delivers them until r ends
+func (s *eventStream) read(ctx context.Context, r io.Reader) (bool, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxEventLine)
+	scanner.Split(scanEventLines)
+
+	received := false
+	eventType := ""
+	var data strings.Builder
+	for first := true; scanner.Scan(); first = false {
+		line := scanner.Text()
+		if first {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+
+		if line == "" {
+			// A blank line dispatches the event
+			if data.Len() > 0 {
+				event := Event{
+					ID:    s.lastEventID,
+					Event: eventType,
+					Data:  strings.TrimSuffix(data.String(), "\n"),
+				}
+				if event.Event == "" {
+					event.Event = "message"
+				}
+				select {
+				case s.events <- event:
+					received = true
+				case <-ctx.Done():
+					return received, ctx.Err()
+				}
+			}
+			eventType = ""
+			data.Reset()
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue // comment
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			eventType = value
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				s.lastEventID = value
+			}
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && strings.Trim(value, "0123456789") == "" {
+				s.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return received, err
+	}
+	return received, io.ErrUnexpectedEOF
+}
+
+// scanEventLines is a bufio.SplitFunc for lines ended by CRLF, LF or CR
+func scanEventLines(data []byte, atEOF bool) (int, []byte, error) {
+	i := bytes.IndexAny(data, "\r\n")
+	if i < 0 {
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+	if data[i] == '\r' {
+		if i+1 == len(data) && !atEOF {
+			return 0, nil, nil // a LF may follow
+		}
+		if i+1 < len(data) && data[i+1] == '\n' {
+			return i + 2, data[:i], nil
+		}
+	}
+	return i + 1, data[:i], nil
+}
+
+// streamKey is the context key of a streamedBody
+type streamKey struct{}
+
+// streamedBody receives the unread body of a streamed response
+type streamedBody struct {
+	mu   sync.Mutex
+	body io.ReadCloser
+}
+
+// withStream marks the requests sent with ctx as streamed: the body of a
+// successful response is handed to the returned streamedBody instead of
+// being read into Response.Body
+func withStream(ctx context.Context) (context.Context, *streamedBody) {
+	stream := &streamedBody{}
+	return context.WithValue(ctx, streamKey{}, stream), stream
+}
+
+// streamFromContext returns the streamedBody of ctx, if any
+func streamFromContext(ctx context.Context) *streamedBody {
+	stream, _ := ctx.Value(streamKey{}).(*streamedBody)
+	return stream
+}
+
+// isStreaming reports whether the response body of req is streamed.
+// Middleware that ends the lifetime of a request when it returns, or that
+// shares or stores response bodies, passes such requests through.
+func isStreaming(req *http.Request) bool {
+	return streamFromContext(req.Context()) != nil
+}
+
+// set hands over body, closing any body handed over before
+func (s *streamedBody) set(body io.ReadCloser) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.body != nil {
+		s.body.Close()
+	}
+	s.body = body
+}
+
+// take returns the body handed over, if any
+func (s *streamedBody) take() io.ReadCloser {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	body := s.body
+	s.body = nil
+	return body
+}
@@ -0,0 +1,140 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_Subscribe(t *testing.T) {
+	var connections int32
+	var mu sync.Mutex
+	var lastEventIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, _, _ := r.BasicAuth(); user != "user" || r.Header.Get("Accept") != "text/event-stream" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		lastEventIDs = append(lastEventIDs, r.Header.Get("Last-Event-ID"))
+		mu.Unlock()
+
+		switch atomic.AddInt32(&connections, 1) {
+		case 1:
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, ": hello\nretry: 10\n\nid: 1\ndata: first\n\n")
+			w.(http.Flusher).Flush()
+			// Outlive the client timeout
+			time.Sleep(100 * time.Millisecond)
+			fmt.Fprint(w, "id: 2\nevent: update\ndata: line one\ndata:line two\n\ndata: unterminated")
+		case 2:
+			w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+			fmt.Fprint(w, "id: 3\r\ndata: {\"n\":3}\r\n\r\n")
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(
+		WithBaseURL(server.URL),
+		WithAuth("user", "secret"),
+		WithTimeout(50*time.Millisecond),
+		WithMiddleware(TimeoutMiddleware(50*time.Millisecond)),
+	)
+
+	var failures []error
+	var events []Event
+	for event := range client.Subscribe(context.Background(), "/events", &EventSourceOptions{
+		Retry:   time.Second,
+		OnError: func(err error) { failures = append(failures, err) },
+	}) {
+		events = append(events, event)
+	}
+
+	expected := []Event{
+		{ID: "1", Event: "message", Data: "first"},
+		{ID: "2", Event: "update", Data: "line one\nline two"},
+		{ID: "3", Event: "message", Data: `{"n":3}`},
+	}
+	if fmt.Sprint(events) != fmt.Sprint(expected) {
+		t.Errorf("Expected events %v, got %v", expected, events)
+	}
+	if fmt.Sprint(lastEventIDs) != "[ 2 3]" {
+		t.Errorf("Expected reconnections to resume from the last event ID, got %q", lastEventIDs)
+	}
+	if len(failures) != 2 || !errors.Is(failures[0], io.ErrUnexpectedEOF) {
+		t.Errorf("Expected 2 dropped connections, got %v", failures)
+	}
+}
+
+func TestClient_SubscribeRetry(t *testing.T) {
+	var mu sync.Mutex
+	var attempts []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts = append(attempts, time.Now())
+		mu.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+	var failures int32
+	client := NewClient(WithBaseURL(server.URL))
+	for range client.Subscribe(ctx, "/events", &EventSourceOptions{
+		Retry:    10 * time.Millisecond,
+		MaxRetry: 40 * time.Millisecond,
+		OnError: func(err error) {
+			var statusErr *StatusError
+			if errors.As(err, &statusErr) {
+				atomic.AddInt32(&failures, 1)
+			}
+		},
+	}) {
+		t.Errorf("Expected no events")
+	}
+
+	// Delays of 10, 20, 40, 40... ms
+	mu.Lock()
+	defer mu.Unlock()
+	if len(attempts) < 4 || len(attempts) > 6 {
+		t.Errorf("Expected 4 to 6 attempts with backoff, got %d", len(attempts))
+	}
+	if int(atomic.LoadInt32(&failures)) < len(attempts)-1 {
+		t.Errorf("Expected status errors to be reported, got %d", failures)
+	}
+}
+
+func TestEventStream_Read(t *testing.T) {
+	events := make(chan Event, 10)
+	s := &eventStream{events: events, retry: time.Second}
+
+	input := "\ufeffdata: a\r\rdata\r\nretry: x\nevent: ping\nid: 7\n\n: only a comment\n\ndata:  spaced\nretry: 250\n\n"
+	received, err := s.read(context.Background(), strings.NewReader(input))
+	close(events)
+
+	if !received || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected events and an unexpected EOF, got %v, %v", received, err)
+	}
+	var got []string
+	for event := range events {
+		got = append(got, fmt.Sprintf("%s/%s/%q", event.ID, event.Event, event.Data))
+	}
+	expected := []string{`/message/"a"`, `7/ping/""`, `7/message/" spaced"`}
+	if fmt.Sprint(got) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+	if s.retry != 250*time.Millisecond || s.lastEventID != "7" {
+		t.Errorf("Expected retry 250ms and last event ID 7, got %v and %q", s.retry, s.lastEventID)
+	}
+}
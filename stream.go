@@ -0,0 +1,142 @@
+package httpclient
+
+import (
+	"context"
+	"sync"
+)
+
+// RequestSource yields requests to SendConcurrentStream until yield
+// returns false or ctx is done
+type RequestSource func(ctx context.Context, yield func(ConcurrentRequest) bool)
+
+// RequestsFromSlice returns a source yielding requests in order
+func RequestsFromSlice(requests []ConcurrentRequest) RequestSource {
+	return func(ctx context.Context, yield func(ConcurrentRequest) bool) {
+		for _, req := range requests {
+			if !yield(req) {
+				return
+			}
+		}
+	}
+}
+
+// RequestsFromChannel returns a source yielding requests received from ch
+// until it is closed
+func RequestsFromChannel(ch <-chan ConcurrentRequest) RequestSource {
+	return func(ctx context.Context, yield func(ConcurrentRequest) bool) {
+		for {
+			select {
+			case req, ok := <-ch:
+				if !ok || !yield(req) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// RequestsFromIterator returns a source yielding the requests of an
+// iterator function, such as an iter.Seq[ConcurrentRequest]
+func RequestsFromIterator(seq func(yield func(ConcurrentRequest) bool)) RequestSource {
+	return func(ctx context.Context, yield func(ConcurrentRequest) bool) {
+		seq(yield)
+	}
+}
+
+// StreamOptions configures SendConcurrentStream
+type StreamOptions struct {
+	// FailFast stops the stream after the first failed request
+	FailFast bool
+	// MaxErrors stops the stream once this many requests failed; zero
+	// means no limit
+	MaxErrors int
+}
+
+// indexedRequest is a request of a stream and its position in the source
+type indexedRequest struct {
+	index int
+	req   ConcurrentRequest
+}
+
+// SendConcurrentStream sends the requests of source with at most limit in
+// flight and delivers each result as soon as it completes. Index is the
+// position of the request in the source.
+//
+// The channel is closed once every request has completed, or after the
+// stream stopped because of FailFast or MaxErrors; requests still in flight
+// are then cancelled and their results dropped.
+//
+// The goroutines of the stream block until their results are received. A
+// consumer that stops receiving early must cancel ctx, which cancels the
+// remaining requests and releases every goroutine.
+func (ac *AsyncClient) SendConcurrentStream(ctx context.Context, source RequestSource, limit int, options *StreamOptions) <-chan ConcurrentResponse {
+	if options == nil {
+		options = &StreamOptions{}
+	}
+	if limit <= 0 {
+		limit = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	out := make(chan ConcurrentResponse)
+	requests := make(chan indexedRequest)
+
+	go func() {
+		defer close(requests)
+
+		index := 0
+		source(ctx, func(req ConcurrentRequest) bool {
+			select {
+			case requests <- indexedRequest{index: index, req: req}:
+				index++
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+
+	var mu sync.Mutex
+	failures := 0
+	var wg sync.WaitGroup
+
+	for i := 0; i < limit; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for r := range requests {
+				resp, err := ac.RequestWithContext(ctx, r.req.Method, r.req.Path, r.req.Options)
+				if ctx.Err() != nil {
+					continue
+				}
+
+				stop := false
+				if err != nil {
+					mu.Lock()
+					failures++
+					stop = options.FailFast || (options.MaxErrors > 0 && failures >= options.MaxErrors)
+					mu.Unlock()
+				}
+
+				select {
+				case out <- ConcurrentResponse{Index: r.index, Response: resp, Error: err}:
+				case <-ctx.Done():
+					continue
+				}
+				if stop {
+					cancel()
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		cancel()
+		close(out)
+	}()
+
+	return out
+}
@@ -0,0 +1,201 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newStreamServer(calls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		if strings.HasPrefix(r.URL.Path, "/slow") {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		if r.URL.Path == "/broken" {
+			// Drop the connection so the client sees a transport error
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		w.Write([]byte(r.URL.Path))
+	}))
+}
+
+func TestSendConcurrentStream(t *testing.T) {
+	var calls int32
+	server := newStreamServer(&calls)
+	defer server.Close()
+	client := NewAsyncClient(WithBaseURL(server.URL))
+
+	requests := make(chan ConcurrentRequest)
+	go func() {
+		defer close(requests)
+		for _, path := range []string{"/a", "/b", "/c", "/d", "/e"} {
+			requests <- ConcurrentRequest{Method: "GET", Path: path}
+		}
+	}()
+
+	results := client.SendConcurrentStream(context.Background(), RequestsFromChannel(requests), 2, nil)
+
+	seen := make(map[int]string)
+	for result := range results {
+		if result.Error != nil {
+			t.Fatalf("Expected no error, got %v", result.Error)
+		}
+		seen[result.Index] = result.Response.GetBody()
+	}
+
+	if len(seen) != 5 || seen[0] != "/a" || seen[4] != "/e" {
+		t.Errorf("Expected every result with its index, got %v", seen)
+	}
+}
+
+func TestSendConcurrentStream_Iterator(t *testing.T) {
+	var calls int32
+	server := newStreamServer(&calls)
+	defer server.Close()
+	client := NewAsyncClient(WithBaseURL(server.URL))
+
+	paths := func(yield func(ConcurrentRequest) bool) {
+		for _, path := range []string{"/a", "/b", "/c"} {
+			if !yield(ConcurrentRequest{Method: "GET", Path: path}) {
+				return
+			}
+		}
+	}
+
+	results := client.SendConcurrentStream(context.Background(), RequestsFromIterator(paths), 3, nil)
+
+	count := 0
+	for range results {
+		count++
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 results, got %d", count)
+	}
+}
+
+func TestSendConcurrentStream_FailFast(t *testing.T) {
+	var calls int32
+	server := newStreamServer(&calls)
+	defer server.Close()
+	client := NewAsyncClient(WithBaseURL(server.URL))
+
+	requests := []ConcurrentRequest{{Method: "GET", Path: "/broken"}}
+	for i := 0; i < 20; i++ {
+		requests = append(requests, ConcurrentRequest{Method: "GET", Path: "/ok"})
+	}
+
+	results := client.SendConcurrentStream(context.Background(), RequestsFromSlice(requests), 1, &StreamOptions{FailFast: true})
+
+	var errs int
+	for result := range results {
+		if result.Error != nil {
+			errs++
+		}
+	}
+	if errs != 1 {
+		t.Errorf("Expected the failure to be delivered, got %d errors", errs)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("Expected the stream to stop after the failure, got %d requests", n)
+	}
+}
+
+func TestSendConcurrentStream_MaxErrors(t *testing.T) {
+	var calls int32
+	server := newStreamServer(&calls)
+	defer server.Close()
+	client := NewAsyncClient(WithBaseURL(server.URL))
+
+	var requests []ConcurrentRequest
+	for i := 0; i < 10; i++ {
+		requests = append(requests, ConcurrentRequest{Method: "GET", Path: "/broken"})
+	}
+
+	results := client.SendConcurrentStream(context.Background(), RequestsFromSlice(requests), 1, &StreamOptions{MaxErrors: 3})
+
+	var errs int
+	for result := range results {
+		if result.Error != nil {
+			errs++
+		}
+	}
+	if errs != 3 {
+		t.Errorf("Expected 3 errors before stopping, got %d", errs)
+	}
+}
+
+func TestSendConcurrentStream_EarlyStop(t *testing.T) {
+	var calls int32
+	server := newStreamServer(&calls)
+	defer server.Close()
+	client := NewAsyncClient(WithBaseURL(server.URL))
+	before := runtime.NumGoroutine()
+
+	// An endless source of requests, some of which never complete
+	endless := func(ctx context.Context, yield func(ConcurrentRequest) bool) {
+		for i := 0; ; i++ {
+			path := "/fast"
+			if i%2 == 1 {
+				path = "/slow"
+			}
+			if !yield(ConcurrentRequest{Method: "GET", Path: path}) {
+				return
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	results := client.SendConcurrentStream(ctx, endless, 4, nil)
+	<-results
+	cancel()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before+2 && time.Now().Before(deadline) {
+		client.httpClient.CloseIdleConnections()
+		time.Sleep(10 * time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before+2 {
+		t.Errorf("Expected the stream goroutines to exit, got %d more", after-before)
+	}
+}
+
+func TestSendConcurrentStream_CancelAfterBreak(t *testing.T) {
+	// Answer without a transport so that only stream goroutines are counted
+	client := NewAsyncClient(WithMiddleware(func(next Handler) Handler {
+		return func(req *http.Request) (*Response, error) {
+			return &Response{Response: &http.Response{StatusCode: http.StatusOK}}, nil
+		}
+	}))
+	before := runtime.NumGoroutine()
+
+	var requests []ConcurrentRequest
+	for i := 0; i < 100; i++ {
+		requests = append(requests, ConcurrentRequest{Method: "GET", Path: "http://example.com/"})
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	results := client.SendConcurrentStream(ctx, RequestsFromSlice(requests), 8, nil)
+	for range results {
+		break // the consumer stops ranging
+	}
+	cancel()
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("Expected cancelling ctx to release the stream goroutines, got %d more", after-before)
+	}
+}
@@ -0,0 +1,126 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timings represents the timing breakdown of a single request
+type Timings struct {
+	DNSLookup    time.Duration
+	Connect      time.Duration
+	TLSHandshake time.Duration
+	FirstByte    time.Duration // time from writing the request to the first response byte
+	BodyRead     time.Duration
+	Total        time.Duration
+	ConnReused   bool
+	RemoteAddr   string
+}
+
+// timingTracer collects Timings through httptrace hooks
+type timingTracer struct {
+	mu           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	wroteRequest time.Time
+	bodyStart    time.Time
+	timings      Timings
+}
+
+// newTimingTracer creates a tracer whose clock starts now
+func newTimingTracer() *timingTracer {
+	return &timingTracer{start: time.Now()}
+}
+
+// clientTrace returns the httptrace hooks feeding the tracer
+func (tt *timingTracer) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			tt.mu.Lock()
+			tt.dnsStart = time.Now()
+			tt.mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			tt.mu.Lock()
+			tt.timings.DNSLookup = time.Since(tt.dnsStart)
+			tt.mu.Unlock()
+		},
+		ConnectStart: func(network, addr string) {
+			tt.mu.Lock()
+			if tt.connectStart.IsZero() {
+				tt.connectStart = time.Now()
+			}
+			tt.mu.Unlock()
+		},
+		ConnectDone: func(network, addr string, err error) {
+			tt.mu.Lock()
+			if err == nil {
+				tt.timings.Connect = time.Since(tt.connectStart)
+			}
+			tt.mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			tt.mu.Lock()
+			tt.tlsStart = time.Now()
+			tt.mu.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			tt.mu.Lock()
+			tt.timings.TLSHandshake = time.Since(tt.tlsStart)
+			tt.mu.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			tt.mu.Lock()
+			tt.timings.ConnReused = info.Reused
+			if info.Conn != nil {
+				tt.timings.RemoteAddr = info.Conn.RemoteAddr().String()
+			}
+			tt.mu.Unlock()
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			tt.mu.Lock()
+			tt.wroteRequest = time.Now()
+			tt.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			tt.mu.Lock()
+			sent := tt.wroteRequest
+			if sent.IsZero() {
+				sent = tt.start
+			}
+			tt.timings.FirstByte = time.Since(sent)
+			tt.mu.Unlock()
+		},
+	}
+}
+
+// headersReceived marks the start of the body transfer
+func (tt *timingTracer) headersReceived() {
+	tt.mu.Lock()
+	tt.bodyStart = time.Now()
+	tt.mu.Unlock()
+}
+
+// finish stops the clock and returns the collected timings
+func (tt *timingTracer) finish() *Timings {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+
+	now := time.Now()
+	if !tt.bodyStart.IsZero() {
+		tt.timings.BodyRead = now.Sub(tt.bodyStart)
+	}
+	tt.timings.Total = now.Sub(tt.start)
+
+	timings := tt.timings
+	return &timings
+}
+
+// GetTimings returns the timing breakdown of the request
+func (r *Response) GetTimings() *Timings {
+	return r.Timings
+}
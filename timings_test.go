@@ -0,0 +1,53 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestResponse_Timings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"message": "success"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(WithBaseURL(server.URL))
+	resp, err := client.Get("/test", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	timings := resp.GetTimings()
+	if timings == nil {
+		t.Fatal("Expected timings to be set")
+	}
+
+	if timings.FirstByte < 20*time.Millisecond {
+		t.Errorf("Expected time to first byte >= 20ms, got %v", timings.FirstByte)
+	}
+
+	if timings.Total < timings.Connect+timings.FirstByte {
+		t.Errorf("Expected total %v to be >= connect %v plus time to first byte %v", timings.Total, timings.Connect, timings.FirstByte)
+	}
+
+	if timings.RemoteAddr != server.Listener.Addr().String() {
+		t.Errorf("Expected remote address '%s', got '%s'", server.Listener.Addr().String(), timings.RemoteAddr)
+	}
+
+	if timings.ConnReused {
+		t.Error("Expected first connection not to be reused")
+	}
+
+	resp, err = client.Get("/test", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !resp.GetTimings().ConnReused {
+		t.Error("Expected second connection to be reused")
+	}
+}
@@ -0,0 +1,306 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceID identifies a trace
+type TraceID [16]byte
+
+// SpanID identifies a span within a trace
+type SpanID [8]byte
+
+// SpanContext is the propagated part of a span
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Sampled    bool
+	TraceState string
+}
+
+// IsValid reports whether the trace and span IDs are set
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// Traceparent formats the span context as a W3C traceparent header value
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + hex.EncodeToString(sc.TraceID[:]) + "-" + hex.EncodeToString(sc.SpanID[:]) + "-" + flags
+}
+
+// ParseTraceparent parses a W3C traceparent header value
+func ParseTraceparent(value string) (SpanContext, error) {
+	var sc SpanContext
+
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return sc, fmt.Errorf("httpclient: invalid traceparent %q", value)
+	}
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, fmt.Errorf("httpclient: invalid traceparent %q", value)
+	}
+
+	traceID, err := hex.DecodeString(parts[1])
+	if err != nil {
+		return sc, fmt.Errorf("httpclient: invalid traceparent %q", value)
+	}
+	spanID, err := hex.DecodeString(parts[2])
+	if err != nil {
+		return sc, fmt.Errorf("httpclient: invalid traceparent %q", value)
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return sc, fmt.Errorf("httpclient: invalid traceparent %q", value)
+	}
+
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Sampled = flags[0]&1 == 1
+	if !sc.IsValid() {
+		return sc, fmt.Errorf("httpclient: invalid traceparent %q", value)
+	}
+
+	return sc, nil
+}
+
+// ContextWithSpanContext returns a context carrying sc as the current span
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey, sc)
+}
+
+// SpanContextFromContext returns the current span context stored in ctx
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(spanContextKey).(SpanContext)
+	return sc, ok && sc.IsValid()
+}
+
+// Span is a single traced operation
+type Span interface {
+	SpanContext() SpanContext
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+// Tracer starts spans. It is small enough to be implemented by an
+// adapter around an OpenTelemetry tracer.
+type Tracer interface {
+	// Start starts a client span as a child of the span in ctx and returns
+	// a context carrying the new span
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// TracingOptions configures TracingMiddleware
+type TracingOptions struct {
+	B3 bool // also inject X-B3-* headers
+}
+
+// TracingMiddleware opens a client span per attempt and per redirect and
+// injects W3C traceparent/tracestate headers. Place it after
+// RetryMiddleware to get a span per retry. With a nil tracer the span
+// context found in the request context is propagated unchanged.
+func TracingMiddleware(tracer Tracer, options *TracingOptions) Middleware {
+	if options == nil {
+		options = &TracingOptions{}
+	}
+
+	return func(next Handler) Handler {
+		return func(req *http.Request) (*Response, error) {
+			if tracer == nil {
+				if sc, ok := SpanContextFromContext(req.Context()); ok {
+					req = req.Clone(req.Context())
+					injectSpanContext(req.Header, sc, options.B3)
+				}
+				return next(req)
+			}
+
+			var mu sync.Mutex
+			var span Span
+
+			parent := req.Context()
+			ctx := withRedirectHook(parent, func(redirect *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				span.SetAttribute("http.redirected", true)
+				span.End()
+				_, span = startClientSpan(parent, tracer, redirect, options.B3)
+			})
+			req = req.Clone(ctx)
+			_, span = startClientSpan(parent, tracer, req, options.B3)
+
+			resp, err := next(req)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				span.RecordError(err)
+			} else {
+				span.SetAttribute("http.status_code", resp.StatusCode)
+				if resp.StatusCode >= 500 {
+					span.RecordError(fmt.Errorf("httpclient: server responded with status %d", resp.StatusCode))
+				}
+			}
+			span.End()
+
+			return resp, err
+		}
+	}
+}
+
+// startClientSpan starts a span for req and injects it into the request headers
+func startClientSpan(ctx context.Context, tracer Tracer, req *http.Request, b3 bool) (context.Context, Span) {
+	ctx, span := tracer.Start(ctx, "HTTP "+req.Method+" "+routeOf(req))
+	span.SetAttribute("http.method", req.Method)
+	span.SetAttribute("http.url", req.URL.String())
+	span.SetAttribute("http.route", routeOf(req))
+	span.SetAttribute("net.peer.name", req.URL.Hostname())
+	span.SetAttribute("http.attempt", AttemptFromContext(req.Context()))
+	injectSpanContext(req.Header, span.SpanContext(), b3)
+	return ctx, span
+}
+
+// injectSpanContext writes the propagation headers for sc
+func injectSpanContext(header http.Header, sc SpanContext, b3 bool) {
+	if !sc.IsValid() {
+		return
+	}
+
+	header.Set("traceparent", sc.Traceparent())
+	if sc.TraceState != "" {
+		header.Set("tracestate", sc.TraceState)
+	} else {
+		header.Del("tracestate")
+	}
+
+	if b3 {
+		header.Set("X-B3-TraceId", hex.EncodeToString(sc.TraceID[:]))
+		header.Set("X-B3-SpanId", hex.EncodeToString(sc.SpanID[:]))
+		if sc.Sampled {
+			header.Set("X-B3-Sampled", "1")
+		} else {
+			header.Set("X-B3-Sampled", "0")
+		}
+	}
+}
+
+// InMemoryTracer is a Tracer that records spans in memory for tests
+type InMemoryTracer struct {
+	mu    sync.Mutex
+	spans []*RecordedSpan
+}
+
+// NewInMemoryTracer creates a new in-memory tracer
+func NewInMemoryTracer() *InMemoryTracer {
+	return &InMemoryTracer{}
+}
+
+// Start implements Tracer
+func (t *InMemoryTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	span := &RecordedSpan{
+		Name:       name,
+		Attributes: make(map[string]interface{}),
+		StartTime:  time.Now(),
+	}
+
+	if parent, ok := SpanContextFromContext(ctx); ok {
+		span.Parent = parent
+		span.spanContext = SpanContext{
+			TraceID:    parent.TraceID,
+			Sampled:    parent.Sampled,
+			TraceState: parent.TraceState,
+		}
+	} else {
+		rand.Read(span.spanContext.TraceID[:])
+		span.spanContext.Sampled = true
+	}
+	rand.Read(span.spanContext.SpanID[:])
+
+	t.mu.Lock()
+	t.spans = append(t.spans, span)
+	t.mu.Unlock()
+
+	return ContextWithSpanContext(ctx, span.spanContext), span
+}
+
+// Spans returns the spans recorded so far
+func (t *InMemoryTracer) Spans() []*RecordedSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	spans := make([]*RecordedSpan, len(t.spans))
+	copy(spans, t.spans)
+	return spans
+}
+
+// Reset discards all recorded spans
+func (t *InMemoryTracer) Reset() {
+	t.mu.Lock()
+	t.spans = nil
+	t.mu.Unlock()
+}
+
+// RecordedSpan is a span recorded by InMemoryTracer
+type RecordedSpan struct {
+	mu          sync.Mutex
+	Name        string
+	Parent      SpanContext
+	Attributes  map[string]interface{}
+	Err         error
+	StartTime   time.Time
+	EndTime     time.Time
+	spanContext SpanContext
+}
+
+// SpanContext implements Span
+func (s *RecordedSpan) SpanContext() SpanContext {
+	return s.spanContext
+}
+
+// SetAttribute implements Span
+func (s *RecordedSpan) SetAttribute(key string, value interface{}) {
+	s.mu.Lock()
+	s.Attributes[key] = value
+	s.mu.Unlock()
+}
+
+// Attribute returns the value of an attribute
+func (s *RecordedSpan) Attribute(key string) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.Attributes[key]
+}
+
+// RecordError implements Span
+func (s *RecordedSpan) RecordError(err error) {
+	s.mu.Lock()
+	s.Err = errors.Join(s.Err, err)
+	s.mu.Unlock()
+}
+
+// End implements Span
+func (s *RecordedSpan) End() {
+	s.mu.Lock()
+	if s.EndTime.IsZero() {
+		s.EndTime = time.Now()
+	}
+	s.mu.Unlock()
+}
+
+// Ended reports whether End has been called
+func (s *RecordedSpan) Ended() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.EndTime.IsZero()
+}
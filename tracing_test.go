@@ -0,0 +1,105 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTracingMiddleware(t *testing.T) {
+	var traceparents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/new", http.StatusFound)
+			return
+		}
+		if r.Header.Get("X-B3-TraceId") == "" {
+			t.Error("Expected B3 headers to be injected")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tracer := NewInMemoryTracer()
+	client := NewClient(
+		WithBaseURL(server.URL),
+		WithMiddleware(TracingMiddleware(tracer, &TracingOptions{B3: true})),
+	)
+
+	parent, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := ContextWithSpanContext(context.Background(), parent)
+
+	_, err := client.RequestWithContext(ctx, "GET", "/old", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	spans := tracer.Spans()
+	if len(spans) != 2 || len(traceparents) != 2 {
+		t.Fatalf("Expected a span per redirect hop, got %d spans and %d requests", len(spans), len(traceparents))
+	}
+
+	for i, span := range spans {
+		if !span.Ended() {
+			t.Errorf("Expected span %d to be ended", i)
+		}
+		if span.Parent != parent {
+			t.Errorf("Expected span %d to be a child of the context span", i)
+		}
+		if traceparents[i] != span.SpanContext().Traceparent() {
+			t.Errorf("Expected traceparent '%s', got '%s'", span.SpanContext().Traceparent(), traceparents[i])
+		}
+	}
+
+	if spans[1].Attribute("http.status_code") != http.StatusOK {
+		t.Errorf("Expected status code attribute 200, got %v", spans[1].Attribute("http.status_code"))
+	}
+}
+
+func TestTracingMiddleware_Retries(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			hj, _ := w.(http.Hijacker)
+			conn, _, _ := hj.Hijack()
+			conn.Close()
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	tracer := NewInMemoryTracer()
+	client := NewClient(
+		WithBaseURL(server.URL),
+		WithMiddleware(
+			RetryMiddleware(1, &ExponentialBackoff{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}),
+			TracingMiddleware(tracer, nil),
+		),
+	)
+
+	if _, err := client.Post("/users", nil); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	spans := tracer.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("Expected a span per attempt, got %d", len(spans))
+	}
+
+	if spans[0].Err == nil || spans[1].Err != nil {
+		t.Errorf("Expected only the first attempt to fail, got %v and %v", spans[0].Err, spans[1].Err)
+	}
+
+	if spans[1].Attribute("http.attempt") != 2 {
+		t.Errorf("Expected second span attempt to be 2, got %v", spans[1].Attribute("http.attempt"))
+	}
+
+	if spans[0].SpanContext().TraceID == spans[1].SpanContext().TraceID {
+		t.Error("Expected root spans without a parent to start new traces")
+	}
+}